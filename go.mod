@@ -2,6 +2,4 @@ module github.com/rickyangkai/HomeassistantTCPBridge
 
 go 1.21
 
-require (
-	github.com/gorilla/websocket v1.5.1
-)
+require github.com/gorilla/websocket v1.5.3
@@ -1,21 +1,51 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
+	"time"
 
-	"github.com/rick/homeassistant-tcp-bridge/pkg/config"
-	"github.com/rick/homeassistant-tcp-bridge/pkg/ha"
-	"github.com/rick/homeassistant-tcp-bridge/pkg/savant"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/logging"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/metrics"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/profile"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/savant"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/web"
 )
 
 func main() {
+	// Offline tooling: bridge profile generate ...
+	if len(os.Args) > 1 && os.Args[1] == "profile" {
+		os.Exit(profile.Main(os.Args[2:]))
+	}
+
+	// ctx ends on SIGINT or SIGTERM, which starts the shutdown in step 6.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	log.Println("Starting Home Assistant <-> Savant Bridge (Go Version)...")
 
 	// 1. Load Config
-	cfg := config.Load()
+	cfg, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Config: %v", err)
+		}
+		return
+	}
+	logging.SetLevel(cfg.Options.LogLevel)
 
 	// 2. Initialize Components
 	// We need a circular dependency resolution: Savant Server needs HA Client to send commands,
@@ -33,16 +63,155 @@ func main() {
 	}
 
 	haClient := ha.NewClient(cfg.HAWebSocketURL, cfg.SupervisorToken, onHAMessage)
+	haClient.SetTLSConfig(cfg.TLSConfig)
+	haClient.SetShadeOverrides(cfg.ShadeOverrides)
+	haClient.SetSavantTemperatureUnit(cfg.Options.SavantTemperatureUnit)
+	haClient.SetValueFormats(cfg.Options.ValueFormats)
 	savantServer = savant.NewServer(8080, cfg, haClient)
 
+	registry := metrics.NewRegistry()
+	haClient.RegisterMetrics(registry)
+	savantServer.RegisterMetrics(registry)
+
+	var webServer *web.Server
+	if cfg.Options.HTTPPort > 0 {
+		webServer = web.NewServer(cfg.Options.HTTPPort, cfg, haClient)
+		publicURL := webServer.PublicURL(cfg.Options.PublicURL)
+		haClient.SetArtworkBaseURL(publicURL)
+		savantServer.SetHTTPBaseURL(publicURL)
+		webServer.HandleMetrics(registry)
+	}
+
 	// 3. Start Services
 	haClient.Start()
 	go savantServer.Start()
+	if webServer != nil {
+		go webServer.Start()
+	}
 
-	// 4. Wait for Signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	// 4. Reload on SIGHUP or when the options file changes. A reload may
+	// pick another options file, which the watcher then follows. cfg stays
+	// the configuration the process started with.
+	var loaded atomic.Pointer[config.Config]
+	reload := func() {
+		next, err := config.Load(os.Args[1:])
+		if err != nil {
+			log.Printf("Reload: keeping current options: %v", err)
+			return
+		}
+		if restartNeeded(cfg, next) {
+			logging.Warningf("Reload: ha_url, token, TLS and HTTP options take effect after a restart")
+		}
+		logging.SetLevel(next.Options.LogLevel)
+		haClient.SetShadeOverrides(next.ShadeOverrides)
+		haClient.SetSavantTemperatureUnit(next.Options.SavantTemperatureUnit)
+		haClient.SetValueFormats(next.Options.ValueFormats)
+		savantServer.Reload(next)
+		if webServer != nil {
+			webServer.Reload(next)
+		}
+		loaded.Store(next)
+		log.Println("Reload: options applied")
+	}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	loaded.Store(cfg)
+	changed := watchFile(ctx, func() string { return loaded.Load().OptionsFile }, 2*time.Second)
 
+	// 5. Wait for Signal
+	for running := true; running; {
+		select {
+		case <-hupChan:
+			log.Println("Reload: SIGHUP received")
+			reload()
+		case <-changed:
+			log.Printf("Reload: %s changed", loaded.Load().OptionsFile)
+			reload()
+		case <-ctx.Done():
+			running = false
+		}
+	}
+
+	// 6. Shut down: Savant first so no new commands arrive, then flush
+	// what is queued for HA.
 	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := savantServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Savant: Shutdown: %v", err)
+	}
+	if webServer != nil {
+		if err := webServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("HTTP: Shutdown: %v", err)
+		}
+	}
+	if err := haClient.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HA: Shutdown: %v", err)
+	}
+	log.Println("Stopped")
+}
+
+// shutdownTimeout bounds how long shutdown waits for commands in progress
+// and for HA to answer pending service calls.
+const shutdownTimeout = 10 * time.Second
+
+// restartNeeded reports whether next changes anything that is only read
+// at startup, compared with the configuration the process started with.
+// The resolved token covers HA_TOKEN, HA_TOKEN_FILE and the token options.
+func restartNeeded(started, next *config.Config) bool {
+	old, opts := started.Options, next.Options
+	return started.SupervisorToken != next.SupervisorToken ||
+		started.HAWebSocketURL != next.HAWebSocketURL ||
+		old.HACABundle != opts.HACABundle ||
+		old.HAInsecureSkipVerify != opts.HAInsecureSkipVerify ||
+		old.HTTPPort != opts.HTTPPort ||
+		old.PublicURL != opts.PublicURL ||
+		old.ArtworkSize != opts.ArtworkSize ||
+		old.ArtworkFormat != opts.ArtworkFormat
+}
+
+// watchFile polls the file named by path until ctx is done and signals
+// when its modification time or size changes. path is asked on every tick;
+// when it names another file than before, that file becomes the baseline
+// without a signal. Nothing is watched while path is empty.
+func watchFile(ctx context.Context, path func() string, interval time.Duration) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	stat := func(name string) (time.Time, int64) {
+		if name == "" {
+			return time.Time{}, -1
+		}
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		name := path()
+		lastMod, lastSize := stat(name)
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+			next := path()
+			mod, size := stat(next)
+			if next != name {
+				name, lastMod, lastSize = next, mod, size
+				continue
+			}
+			if mod.Equal(lastMod) && size == lastSize {
+				continue
+			}
+			lastMod, lastSize = mod, size
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changed
 }
@@ -1,7 +1,9 @@
 package config
 
 import (
-	"encoding/json"
+	"crypto/tls"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -10,62 +12,254 @@ import (
 type Options struct {
 	ClientIPWhitelist        string `json:"client_ip_whitelist"`
 	EnableGenericCallService bool   `json:"enable_generic_call_service"`
+	EnableSceneActivate      bool   `json:"enable_scene_activate"`
+	EnableScriptRun          bool   `json:"enable_script_run"`
+	EnableAutomationTrigger  bool   `json:"enable_automation_trigger"`
+	EnableAutomationToggle   bool   `json:"enable_automation_toggle"`
 	UseTLS                   bool   `json:"use_tls"`
+	// SavantTemperatureUnit is "C" or "F"; empty means no conversion.
+	SavantTemperatureUnit string `json:"savant_temperature_unit"`
+	// AlarmCode is used when Savant sends an alarm command without a code.
+	AlarmCode string `json:"alarm_code"`
+	// AlarmCheckCodeFormat validates codes against the panel's code_format.
+	AlarmCheckCodeFormat bool `json:"alarm_check_code_format"`
+
+	// HTTPPort serves artwork to Savant; 0 disables the HTTP endpoint.
+	HTTPPort int `json:"http_port"`
+	// PublicURL is how Savant reaches the HTTP endpoint, e.g.
+	// http://192.168.1.20:8081. Detected from the host address when empty.
+	PublicURL string `json:"public_url"`
+	// ArtworkSize limits the longest edge of served artwork; 0 keeps it.
+	ArtworkSize int `json:"artwork_size"`
+	// ArtworkFormat is "original", "jpeg" or "png".
+	ArtworkFormat string `json:"artwork_format"`
+	// Cameras lists the camera entities whose snapshots are served.
+	Cameras []string `json:"cameras"`
+
+	// LogLevel is "debug", "info" or "warning".
+	LogLevel string `json:"log_level"`
+	// DisabledCommands lists Savant commands the bridge refuses.
+	DisabledCommands []string `json:"disabled_commands"`
+
+	// HAURL runs the bridge outside Home Assistant OS, e.g.
+	// https://ha.example.com:8123. Empty means the Supervisor proxy.
+	HAURL string `json:"ha_url"`
+	// HAToken is a long-lived access token for HAURL. HA_TOKEN and
+	// HA_TOKEN_FILE in the environment, or HATokenFile, take precedence.
+	HAToken     string `json:"ha_token"`
+	HATokenFile string `json:"ha_token_file"`
+	// HACABundle is a PEM file of extra CAs trusted for HAURL.
+	HACABundle           string `json:"ha_ca_bundle"`
+	HAInsecureSkipVerify bool   `json:"ha_insecure_skip_verify"`
+
+	ShadeOverrides []ShadeOverride `json:"shade_overrides"`
+	LockCodes      []LockCode      `json:"lock_codes"`
+	ValueFormats   []ValueFormat   `json:"value_formats"`
+}
+
+// LockCode is the default PIN sent with lock commands for one lock.
+type LockCode struct {
+	EntityID string `json:"entity_id"`
+	Code     string `json:"code"`
+}
+
+// ShadeOverride adapts a cover whose position range differs from Savant's
+// 0 (closed) to 100 (open) convention.
+type ShadeOverride struct {
+	EntityID string `json:"entity_id"`
+	Invert   bool   `json:"invert"`
+	Min      int    `json:"min"`
+	Max      int    `json:"max"`
+}
+
+// ToHA maps a Savant level (0-100) to the cover's HA position.
+func (o ShadeOverride) ToHA(level int) int {
+	level = clampPercent(level)
+	if o.Invert {
+		level = 100 - level
+	}
+	return o.Min + (level*(o.Max-o.Min)+50)/100
+}
+
+// ToSavant maps an HA cover position back to a Savant level (0-100).
+func (o ShadeOverride) ToSavant(pos int) int {
+	if o.Max <= o.Min {
+		return clampPercent(pos)
+	}
+	level := clampPercent(((pos-o.Min)*100 + (o.Max-o.Min)/2) / (o.Max - o.Min))
+	if o.Invert {
+		level = 100 - level
+	}
+	return level
+}
+
+func clampPercent(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 100 {
+		return 100
+	}
+	return v
 }
 
 type Config struct {
 	SupervisorToken string
 	HAWebSocketURL  string
+	HAHTTPURL       string
 	Options         Options
 	Whitelist       []string
+	ShadeOverrides  map[string]ShadeOverride // entity_id -> override
+	LockCodes       map[string]string        // entity_id -> code
+	Policy          Policy
+	Cameras         map[string]bool // camera entity_ids served over HTTP
+	TLSConfig       *tls.Config     // for HAURL; nil uses the defaults
+	OptionsFile     string          // file the options were read from, if any
+	PrintConfig     bool            // -print-config: print and exit
+}
+
+// Load reads the configuration from its layers (see layers.go), validates
+// it and returns the first problem as an error. args are the command line
+// arguments without the program name.
+func Load(args []string) (*Config, error) {
+	// 1. Find the options file and mode flags on the command line
+	var (
+		scratch     Options
+		optionsFile string
+		printConfig bool
+	)
+	if err := newFlagSet(&scratch, &optionsFile, &printConfig).Parse(args); err != nil {
+		return nil, err
+	}
+	explicit := optionsFile != ""
+	if !explicit {
+		if env := os.Getenv("BRIDGE_OPTIONS"); env != "" {
+			optionsFile, explicit = env, true
+		} else {
+			optionsFile = defaultOptionsFile
+		}
+	}
+
+	// 2. Layer defaults, options file, environment and flags
+	opts := defaultOptions()
+	used, err := readOptionsFile(optionsFile, explicit, &opts)
+	if err != nil {
+		return nil, err
+	}
+	if used != "" {
+		log.Printf("Loaded configuration from %s", used)
+	} else {
+		log.Println("No options.json found, using defaults")
+	}
+	if unknown := unknownEnv(&opts); len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown environment variables: %s", strings.Join(unknown, ", "))
+	}
+	if err := applyEnv(&opts); err != nil {
+		return nil, err
+	}
+	fs := newFlagSet(&opts, new(string), new(bool))
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%v", err)
+	}
+	cfg, err := New(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg.OptionsFile = used
+	cfg.PrintConfig = printConfig
+	return cfg, nil
 }
 
-func Load() *Config {
-	// 1. Load Environment Variables
+// New builds the runtime configuration from validated options.
+func New(opts Options) (*Config, error) {
 	token := os.Getenv("SUPERVISOR_TOKEN")
-	if token == "" {
+	if token == "" && opts.HAURL == "" {
 		log.Println("Warning: SUPERVISOR_TOKEN not found in environment")
 	}
 
-	// 2. Load Options from JSON
-	optionsFile := "/data/options.json"
-	// Fallback for local development if file doesn't exist
-	if _, err := os.Stat(optionsFile); os.IsNotExist(err) {
-		optionsFile = "options.json" // Try local dir
+	// 1. Parse Whitelist
+	whitelist := splitList(opts.ClientIPWhitelist)
+
+	// 2. Index Shade Overrides
+	shadeOverrides := make(map[string]ShadeOverride)
+	for _, o := range opts.ShadeOverrides {
+		if o.EntityID == "" {
+			continue
+		}
+		if o.Max == 0 {
+			o.Max = 100
+		}
+		o.Min, o.Max = clampPercent(o.Min), clampPercent(o.Max)
+		if o.Min >= o.Max {
+			log.Printf("Ignoring shade override for %s: min %d must be below max %d", o.EntityID, o.Min, o.Max)
+			continue
+		}
+		shadeOverrides[o.EntityID] = o
+	}
+
+	// 3. Index Lock Codes
+	lockCodes := make(map[string]string)
+	for _, lc := range opts.LockCodes {
+		if lc.EntityID != "" && lc.Code != "" {
+			lockCodes[lc.EntityID] = lc.Code
+		}
 	}
 
-	var opts Options
-	if _, err := os.Stat(optionsFile); err == nil {
-		content, err := os.ReadFile(optionsFile)
-		if err != nil {
-			log.Printf("Error reading options file: %v", err)
-		} else {
-			if err := json.Unmarshal(content, &opts); err != nil {
-				log.Printf("Error parsing options file: %v", err)
-			} else {
-				log.Printf("Loaded configuration from %s", optionsFile)
-			}
+	// 4. Index Cameras
+	cameras := make(map[string]bool)
+	for _, c := range opts.Cameras {
+		if c = strings.TrimSpace(c); c != "" {
+			cameras[c] = true
 		}
-	} else {
-		log.Println("No options.json found, using defaults")
 	}
 
-	// 3. Parse Whitelist
-	var whitelist []string
-	if opts.ClientIPWhitelist != "" {
-		parts := strings.Split(opts.ClientIPWhitelist, ",")
-		for _, p := range parts {
-			trimmed := strings.TrimSpace(p)
-			if trimmed != "" {
-				whitelist = append(whitelist, trimmed)
-			}
+	// 5. Resolve the Home Assistant connection
+	wsURL, httpURL := supervisorWebSocketURL, supervisorHTTPURL // Default for HAOS
+	var tlsCfg *tls.Config
+	if opts.HAURL != "" {
+		var err error
+		if wsURL, httpURL, err = haEndpoints(opts.HAURL); err != nil {
+			return nil, err
+		}
+		if token, err = haToken(opts); err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, fmt.Errorf("ha_url is set but no access token was found (HA_TOKEN, HA_TOKEN_FILE, ha_token_file or ha_token)")
 		}
+		if tlsCfg, err = tlsConfig(opts); err != nil {
+			return nil, err
+		}
+		if opts.HAInsecureSkipVerify {
+			log.Println("Warning: TLS certificate verification for Home Assistant is disabled")
+		}
+		log.Printf("Standalone mode: using %s", httpURL)
 	}
 
 	return &Config{
 		SupervisorToken: token,
-		HAWebSocketURL:  "ws://supervisor/core/api/websocket", // Default for HAOS
+		HAWebSocketURL:  wsURL,
+		HAHTTPURL:       httpURL,
+		TLSConfig:       tlsCfg,
 		Options:         opts,
 		Whitelist:       whitelist,
-	}
+		ShadeOverrides:  shadeOverrides,
+		LockCodes:       lockCodes,
+		Cameras:         cameras,
+		Policy: Policy{
+			GenericCallService: opts.EnableGenericCallService,
+			SceneActivate:      opts.EnableSceneActivate,
+			ScriptRun:          opts.EnableScriptRun,
+			AutomationTrigger:  opts.EnableAutomationTrigger,
+			AutomationToggle:   opts.EnableAutomationToggle,
+		},
+	}, nil
 }
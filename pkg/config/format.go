@@ -0,0 +1,43 @@
+package config
+
+// ValueFormat describes how a value is formatted before it is sent to
+// Savant. A rule matches by entity_id or, failing that, by device_class.
+type ValueFormat struct {
+	EntityID    string `json:"entity_id"`
+	DeviceClass string `json:"device_class"`
+	// Attribute defaults to "state".
+	Attribute string `json:"attribute"`
+	// Decimals rounds numeric values; nil keeps the precision.
+	Decimals *int `json:"decimals"`
+	// Unit converts numeric values to this unit (e.g. "kW", "°F").
+	Unit string `json:"unit"`
+	// FromUnit overrides the source unit, which is otherwise taken from
+	// the entity's unit_of_measurement.
+	FromUnit string `json:"from_unit"`
+}
+
+// FindValueFormat returns the rule for an entity attribute. Entity rules
+// take precedence over device class rules.
+func FindValueFormat(rules []ValueFormat, entityID, deviceClass, attribute string) (ValueFormat, bool) {
+	var byClass *ValueFormat
+	for i := range rules {
+		r := &rules[i]
+		attr := r.Attribute
+		if attr == "" {
+			attr = "state"
+		}
+		if attr != attribute {
+			continue
+		}
+		if r.EntityID != "" && r.EntityID == entityID {
+			return *r, true
+		}
+		if byClass == nil && r.EntityID == "" && r.DeviceClass != "" && r.DeviceClass == deviceClass {
+			byClass = r
+		}
+	}
+	if byClass != nil {
+		return *byClass, true
+	}
+	return ValueFormat{}, false
+}
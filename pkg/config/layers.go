@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options are read in layers, each overriding the one before:
+//
+//  1. defaults (the add-on defaults from config.json)
+//  2. the options file (/data/options.json, ./options.json, or -options)
+//  3. BRIDGE_<OPTION> environment variables, e.g. BRIDGE_HTTP_PORT=8082
+//  4. command line flags, e.g. -http-port 8082
+//
+// Lists of strings are given comma-separated in the environment and on the
+// command line, lists of objects as JSON.
+
+const defaultOptionsFile = "/data/options.json"
+
+func defaultOptions() Options {
+	return Options{
+		EnableGenericCallService: true,
+		EnableSceneActivate:      true,
+		EnableScriptRun:          true,
+		EnableAutomationTrigger:  true,
+		EnableAutomationToggle:   true,
+		HTTPPort:                 8081,
+		ArtworkFormat:            "original",
+		LogLevel:                 "info",
+	}
+}
+
+// optionField is one settable field of Options.
+type optionField struct {
+	name  string // json name, e.g. http_port
+	value reflect.Value
+}
+
+func optionFields(opts *Options) []optionField {
+	v := reflect.ValueOf(opts).Elem()
+	t := v.Type()
+	fields := make([]optionField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, optionField{name: name, value: v.Field(i)})
+	}
+	return fields
+}
+
+func (f optionField) envName() string {
+	return "BRIDGE_" + strings.ToUpper(f.name)
+}
+
+func (f optionField) flagName() string {
+	return strings.ReplaceAll(f.name, "_", "-")
+}
+
+// set parses s into the field according to its type.
+func (f optionField) set(s string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", f.name, s)
+		}
+		f.value.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("%s: expected a number, got %q", f.name, s)
+		}
+		f.value.SetInt(int64(n))
+	case reflect.Slice:
+		if f.value.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var list []string
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			f.value.Set(reflect.ValueOf(list))
+			return nil
+		}
+		fallthrough
+	default:
+		ptr := reflect.New(f.value.Type())
+		if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+			return fmt.Errorf("%s: invalid JSON: %v", f.name, err)
+		}
+		f.value.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// optionFlag adapts an option field to the flag package.
+type optionFlag struct{ field optionField }
+
+func (o optionFlag) String() string { return "" }
+
+func (o optionFlag) Set(s string) error { return o.field.set(s) }
+
+func (o optionFlag) IsBoolFlag() bool { return o.field.value.Kind() == reflect.Bool }
+
+// readOptionsFile merges the options file into opts. A missing file is only
+// an error when it was named explicitly. It returns the file used, if any.
+func readOptionsFile(path string, explicit bool, opts *Options) (string, error) {
+	if !explicit {
+		// Fallback for local development if file doesn't exist
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = "options.json"
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && !explicit {
+			return "", nil
+		}
+		return "", fmt.Errorf("reading options file: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(opts); err != nil {
+		return "", fmt.Errorf("parsing %s: %v", path, err)
+	}
+	return path, nil
+}
+
+// applyEnv overrides options from BRIDGE_* environment variables.
+func applyEnv(opts *Options) error {
+	for _, f := range optionFields(opts) {
+		if s, ok := os.LookupEnv(f.envName()); ok {
+			if err := f.set(s); err != nil {
+				return fmt.Errorf("%s: %v", f.envName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// unknownEnv lists BRIDGE_* variables that match no option, which are
+// most likely typos.
+func unknownEnv(opts *Options) []string {
+	known := map[string]bool{"BRIDGE_OPTIONS": true}
+	for _, f := range optionFields(opts) {
+		known[f.envName()] = true
+	}
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "BRIDGE_") && !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// newFlagSet defines the bridge flags: -options, -print-config and one flag
+// per option writing into opts.
+func newFlagSet(opts *Options, optionsFile *string, printConfig *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("bridge", flag.ContinueOnError)
+	fs.StringVar(optionsFile, "options", "", "options file (default "+defaultOptionsFile+" or ./options.json; env BRIDGE_OPTIONS)")
+	fs.BoolVar(printConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+	for _, f := range optionFields(opts) {
+		fs.Var(optionFlag{f}, f.flagName(), "overrides "+f.name+" (env "+f.envName()+")")
+	}
+	return fs
+}
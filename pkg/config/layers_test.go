@@ -0,0 +1,116 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeOptions writes an options file into a temporary directory and
+// returns its path.
+func writeOptions(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "options.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := writeOptions(t, `{"http_port": 8082, "log_level": "debug", "artwork_size": 512, "enable_script_run": false}`)
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		flags []string
+		check func(o Options) bool
+	}{
+		{"defaults", nil, nil, func(o Options) bool {
+			return o.ArtworkFormat == "original" && o.EnableSceneActivate
+		}},
+		{"file over defaults", nil, nil, func(o Options) bool {
+			return o.HTTPPort == 8082 && o.LogLevel == "debug" && !o.EnableScriptRun
+		}},
+		{"env over file", map[string]string{"BRIDGE_HTTP_PORT": "8083", "BRIDGE_ENABLE_SCRIPT_RUN": "true"}, nil, func(o Options) bool {
+			return o.HTTPPort == 8083 && o.EnableScriptRun && o.ArtworkSize == 512
+		}},
+		{"flags over env", map[string]string{"BRIDGE_HTTP_PORT": "8083"}, []string{"-http-port", "8084", "-enable-script-run"}, func(o Options) bool {
+			return o.HTTPPort == 8084 && o.EnableScriptRun
+		}},
+		{"string lists", map[string]string{"BRIDGE_CAMERAS": "camera.door, camera.yard,"}, []string{"-disabled-commands", "lock_open"}, func(o Options) bool {
+			return reflect.DeepEqual(o.Cameras, []string{"camera.door", "camera.yard"}) &&
+				reflect.DeepEqual(o.DisabledCommands, []string{"lock_open"})
+		}},
+		{"object lists as JSON", map[string]string{"BRIDGE_LOCK_CODES": `[{"entity_id": "lock.front", "code": "1234"}]`}, nil, func(o Options) bool {
+			return len(o.LockCodes) == 1 && o.LockCodes[0].EntityID == "lock.front"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(append([]string{"-options", file}, tt.flags...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.OptionsFile != file {
+				t.Errorf("OptionsFile = %q, want %q", cfg.OptionsFile, file)
+			}
+			if !tt.check(cfg.Options) {
+				t.Errorf("unexpected options: %+v", cfg.Options)
+			}
+		})
+	}
+}
+
+func TestLoadOptionsFromEnv(t *testing.T) {
+	t.Setenv("BRIDGE_OPTIONS", writeOptions(t, `{"http_port": 8090}`))
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Options.HTTPPort != 8090 {
+		t.Errorf("HTTPPort = %d, want 8090", cfg.Options.HTTPPort)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		env     map[string]string
+		flags   []string
+		want    string
+	}{
+		{"unknown key", `{"http_prot": 8082}`, nil, nil, `unknown field "http_prot"`},
+		{"unknown env", `{}`, map[string]string{"BRIDGE_HTTP_PROT": "8082"}, nil, "unknown environment variables: BRIDGE_HTTP_PROT"},
+		{"bad env value", `{}`, map[string]string{"BRIDGE_HTTP_PORT": "high"}, nil, `BRIDGE_HTTP_PORT: http_port: expected a number, got "high"`},
+		{"bad env bool", `{}`, map[string]string{"BRIDGE_USE_TLS": "yes please"}, nil, "use_tls: expected true or false"},
+		{"bad env JSON", `{}`, map[string]string{"BRIDGE_SHADE_OVERRIDES": "cover.x"}, nil, "shade_overrides: invalid JSON"},
+		{"unknown flag", `{}`, nil, []string{"-http-prot", "1"}, "flag provided but not defined: -http-prot"},
+		{"extra argument", `{}`, nil, []string{"serve"}, `unexpected argument "serve"`},
+		{"invalid value", `{"http_port": 8080}`, nil, nil, "http_port: 8080 is the Savant TCP port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load(append([]string{"-options", writeOptions(t, tt.options)}, tt.flags...))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMissingExplicitFile(t *testing.T) {
+	_, err := Load([]string{"-options", filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil || !strings.Contains(err.Error(), "reading options file") {
+		t.Errorf("Load error = %v, want a reading error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package config
+
+// Policy controls which commands that can trigger arbitrary behaviour in
+// Home Assistant are accepted from Savant.
+type Policy struct {
+	GenericCallService bool
+	SceneActivate      bool
+	ScriptRun          bool
+	AutomationTrigger  bool
+	AutomationToggle   bool
+}
+
+// Allows reports whether cmd may run. Commands not covered by the policy
+// are always allowed.
+func (p Policy) Allows(cmd string) bool {
+	switch cmd {
+	case "call_service":
+		return p.GenericCallService
+	case "scene_activate":
+		return p.SceneActivate
+	case "script_run":
+		return p.ScriptRun
+	case "automation_trigger":
+		return p.AutomationTrigger
+	case "automation_toggle":
+		return p.AutomationToggle
+	}
+	return true
+}
+
+// AllowsService reports whether a generic call_service may call
+// domain.service, so that call_service cannot reach what the dedicated
+// scene, script and automation commands are denied.
+func (p Policy) AllowsService(domain, service string) bool {
+	switch domain {
+	case "scene":
+		return p.SceneActivate
+	case "script":
+		return p.ScriptRun
+	case "automation":
+		if service == "trigger" {
+			return p.AutomationTrigger
+		}
+		return p.AutomationToggle
+	}
+	return true
+}
+
+// AllowsAreaTargets reports whether generic on/off services may target an
+// area, device, label or floor, which can hold scenes, scripts and
+// automations of any kind. That needs every one of their policies on.
+func (p Policy) AllowsAreaTargets() bool {
+	return p.SceneActivate && p.ScriptRun && p.AutomationTrigger && p.AutomationToggle
+}
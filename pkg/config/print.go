@@ -0,0 +1,47 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+)
+
+const redacted = "****"
+
+// Redacted returns a copy of the options with secrets masked.
+func (o Options) Redacted() Options {
+	mask := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redacted
+	}
+	o.AlarmCode = mask(o.AlarmCode)
+	o.HAToken = mask(o.HAToken)
+	if o.LockCodes != nil {
+		codes := make([]LockCode, len(o.LockCodes))
+		for i, lc := range o.LockCodes {
+			codes[i] = LockCode{EntityID: lc.EntityID, Code: mask(lc.Code)}
+		}
+		o.LockCodes = codes
+	}
+	return o
+}
+
+// Print writes the effective configuration as JSON, with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	token := ""
+	if c.SupervisorToken != "" {
+		token = redacted
+	}
+	out := struct {
+		OptionsFile    string  `json:"options_file"`
+		HAWebSocketURL string  `json:"ha_websocket_url"`
+		HAHTTPURL      string  `json:"ha_http_url"`
+		Token          string  `json:"token"`
+		Options        Options `json:"options"`
+	}{c.OptionsFile, c.HAWebSocketURL, c.HAHTTPURL, token, c.Options.Redacted()}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	supervisorWebSocketURL = "ws://supervisor/core/api/websocket"
+	supervisorHTTPURL      = "http://supervisor/core"
+)
+
+// haEndpoints turns the configured ha_url into the WebSocket and REST base
+// URLs. http, https, ws and wss URLs are accepted, with or without the
+// /api/websocket path.
+func haEndpoints(raw string) (wsURL, httpURL string, err error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", "", fmt.Errorf("invalid ha_url %q: %v", raw, err)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("invalid ha_url %q: missing host", raw)
+	}
+
+	var wsScheme, httpScheme string
+	switch strings.ToLower(u.Scheme) {
+	case "http", "ws":
+		wsScheme, httpScheme = "ws", "http"
+	case "https", "wss":
+		wsScheme, httpScheme = "wss", "https"
+	default:
+		return "", "", fmt.Errorf("invalid ha_url %q: scheme must be http, https, ws or wss", raw)
+	}
+
+	base := strings.TrimSuffix(strings.TrimRight(u.Path, "/"), "/api/websocket")
+	u.RawQuery, u.Fragment = "", ""
+
+	u.Scheme, u.Path = wsScheme, base+"/api/websocket"
+	wsURL = u.String()
+	u.Scheme, u.Path = httpScheme, base
+	httpURL = u.String()
+	return wsURL, httpURL, nil
+}
+
+// haToken finds the long-lived access token for standalone mode: the
+// HA_TOKEN environment variable, then the file named by HA_TOKEN_FILE or
+// ha_token_file, then ha_token.
+func haToken(opts Options) (string, error) {
+	if token := strings.TrimSpace(os.Getenv("HA_TOKEN")); token != "" {
+		return token, nil
+	}
+	file := os.Getenv("HA_TOKEN_FILE")
+	if file == "" {
+		file = opts.HATokenFile
+	}
+	if file != "" {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("reading token file: %v", err)
+		}
+		if token := strings.TrimSpace(string(content)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("token file %s is empty", file)
+	}
+	return strings.TrimSpace(opts.HAToken), nil
+}
+
+// tlsConfig builds the TLS settings for connections to HA, or nil when the
+// defaults apply.
+func tlsConfig(opts Options) (*tls.Config, error) {
+	if opts.HACABundle == "" && !opts.HAInsecureSkipVerify {
+		return nil, nil
+	}
+	cfg := &tls.Config{InsecureSkipVerify: opts.HAInsecureSkipVerify}
+	if opts.HACABundle != "" {
+		pem, err := os.ReadFile(opts.HACABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.HACABundle)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/logging"
+)
+
+// savantPort is the fixed TCP port Savant hosts connect to.
+const savantPort = 8080
+
+// Validate checks every option and returns all problems found, so that a
+// misconfigured bridge refuses to start instead of running on defaults.
+func (o Options) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, ip := range splitList(o.ClientIPWhitelist) {
+		if net.ParseIP(ip) == nil {
+			add("client_ip_whitelist: %q is not an IP address", ip)
+		}
+	}
+	switch o.SavantTemperatureUnit {
+	case "", "C", "F":
+	default:
+		add("savant_temperature_unit: must be C, F or empty, got %q", o.SavantTemperatureUnit)
+	}
+
+	if _, err := logging.ParseLevel(o.LogLevel); err != nil {
+		add("log_level: %v", err)
+	}
+
+	if o.HTTPPort < 0 || o.HTTPPort > 65535 {
+		add("http_port: %d is not a valid port", o.HTTPPort)
+	} else if o.HTTPPort == savantPort {
+		add("http_port: %d is the Savant TCP port", o.HTTPPort)
+	}
+	if o.PublicURL != "" {
+		if u, err := url.Parse(o.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("public_url: %q must be an http:// or https:// URL", o.PublicURL)
+		}
+	}
+	if o.ArtworkSize < 0 || o.ArtworkSize > 2048 {
+		add("artwork_size: must be between 0 and 2048, got %d", o.ArtworkSize)
+	}
+	switch o.ArtworkFormat {
+	case "", "original", "jpeg", "png":
+	default:
+		add("artwork_format: must be original, jpeg or png, got %q", o.ArtworkFormat)
+	}
+	for _, c := range o.Cameras {
+		if !strings.HasPrefix(strings.TrimSpace(c), "camera.") {
+			add("cameras: %q is not a camera entity", c)
+		}
+	}
+
+	for i, s := range o.ShadeOverrides {
+		if !isEntityID(s.EntityID) {
+			add("shade_overrides[%d]: entity_id %q is not an entity ID", i, s.EntityID)
+		}
+		max := s.Max
+		if max == 0 {
+			max = 100
+		}
+		if s.Min < 0 || max > 100 || s.Min >= max {
+			add("shade_overrides[%d] (%s): need 0 <= min < max <= 100, got min %d max %d", i, s.EntityID, s.Min, max)
+		}
+	}
+	for i, lc := range o.LockCodes {
+		if !isEntityID(lc.EntityID) {
+			add("lock_codes[%d]: entity_id %q is not an entity ID", i, lc.EntityID)
+		}
+		if lc.Code == "" {
+			add("lock_codes[%d] (%s): code is empty", i, lc.EntityID)
+		}
+	}
+	for i, vf := range o.ValueFormats {
+		if vf.EntityID == "" && vf.DeviceClass == "" {
+			add("value_formats[%d]: needs entity_id or device_class", i)
+		}
+		if vf.EntityID != "" && !isEntityID(vf.EntityID) {
+			add("value_formats[%d]: entity_id %q is not an entity ID", i, vf.EntityID)
+		}
+		if vf.Decimals != nil && (*vf.Decimals < 0 || *vf.Decimals > 6) {
+			add("value_formats[%d]: decimals must be between 0 and 6, got %d", i, *vf.Decimals)
+		}
+	}
+
+	if o.HAURL != "" {
+		if _, _, err := haEndpoints(o.HAURL); err != nil {
+			errs = append(errs, err)
+		}
+	} else if o.HAToken != "" || o.HATokenFile != "" || o.HACABundle != "" || o.HAInsecureSkipVerify {
+		add("ha_token, ha_token_file, ha_ca_bundle and ha_insecure_skip_verify need ha_url")
+	}
+
+	return errors.Join(errs...)
+}
+
+func isEntityID(id string) bool {
+	i := strings.Index(id, ".")
+	return i > 0 && i < len(id)-1 && !strings.ContainsAny(id, " ,")
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}
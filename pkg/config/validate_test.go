@@ -0,0 +1,96 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	decimals := func(n int) *int { return &n }
+
+	tests := []struct {
+		name   string
+		modify func(o *Options)
+		want   []string // one line per expected problem
+	}{
+		{"defaults", func(o *Options) {}, nil},
+		{"whitelist", func(o *Options) { o.ClientIPWhitelist = "192.168.1.2, nope" }, []string{
+			`client_ip_whitelist: "nope" is not an IP address`,
+		}},
+		{"temperature unit", func(o *Options) { o.SavantTemperatureUnit = "K" }, []string{
+			`savant_temperature_unit: must be C, F or empty, got "K"`,
+		}},
+		{"http port", func(o *Options) { o.HTTPPort = 70000 }, []string{
+			"http_port: 70000 is not a valid port",
+		}},
+		{"public url", func(o *Options) { o.PublicURL = "192.168.1.20:8081" }, []string{
+			`public_url: "192.168.1.20:8081" must be an http:// or https:// URL`,
+		}},
+		{"artwork", func(o *Options) { o.ArtworkSize = 4096; o.ArtworkFormat = "gif" }, []string{
+			"artwork_size: must be between 0 and 2048, got 4096",
+			`artwork_format: must be original, jpeg or png, got "gif"`,
+		}},
+		{"cameras", func(o *Options) { o.Cameras = []string{"camera.door", "light.porch"} }, []string{
+			`cameras: "light.porch" is not a camera entity`,
+		}},
+		{"shade overrides", func(o *Options) {
+			o.ShadeOverrides = []ShadeOverride{{EntityID: "cover.a", Min: 10}, {EntityID: "cover", Min: 50, Max: 40}}
+		}, []string{
+			`shade_overrides[1]: entity_id "cover" is not an entity ID`,
+			"shade_overrides[1] (cover): need 0 <= min < max <= 100, got min 50 max 40",
+		}},
+		{"lock codes", func(o *Options) { o.LockCodes = []LockCode{{EntityID: "lock.front"}} }, []string{
+			"lock_codes[0] (lock.front): code is empty",
+		}},
+		{"value formats", func(o *Options) {
+			o.ValueFormats = []ValueFormat{{Decimals: decimals(7)}, {EntityID: "sensor.t", Decimals: decimals(1)}}
+		}, []string{
+			"value_formats[0]: needs entity_id or device_class",
+			"value_formats[0]: decimals must be between 0 and 6, got 7",
+		}},
+		{"standalone options without ha_url", func(o *Options) { o.HAToken = "secret" }, []string{
+			"ha_token, ha_token_file, ha_ca_bundle and ha_insecure_skip_verify need ha_url",
+		}},
+		{"ha_url", func(o *Options) { o.HAURL = "ftp://ha.local" }, []string{
+			`invalid ha_url "ftp://ha.local": scheme must be http, https, ws or wss`,
+		}},
+		{"all problems are reported", func(o *Options) {
+			o.LogLevel = "loud"
+			o.HTTPPort = savantPort
+			o.Cameras = []string{"sensor.x"}
+		}, []string{
+			"log_level: ",
+			"http_port: 8080 is the Savant TCP port",
+			`cameras: "sensor.x" is not a camera entity`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := defaultOptions()
+			tt.modify(&opts)
+			err := opts.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %d problems", len(tt.want))
+			}
+			joined, ok := err.(interface{ Unwrap() []error })
+			if !ok {
+				t.Fatalf("Validate() = %T, want an errors.Join error", err)
+			}
+			errs := joined.Unwrap()
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Validate() returned %d problems, want %d:\n%v", len(errs), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if !strings.HasPrefix(errs[i].Error(), want) {
+					t.Errorf("problem %d = %q, want prefix %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
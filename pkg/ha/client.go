@@ -1,15 +1,22 @@
 package ha
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
 )
 
 // Message types
@@ -28,19 +35,48 @@ const (
 type Client struct {
 	url           string
 	token         string
-	conn          *websocket.Conn
 	idCounter     int64
 	sendChan      chan interface{}
 	onMessage     func(string) // Callback to send data to Savant
-	isAuth        bool
 	reconnectChan chan bool
+	tlsConfig     *tls.Config // for wss:// in standalone mode
+
+	connMu       sync.RWMutex // Protects conn, isAuth and the auth signals
+	conn         *websocket.Conn
+	isAuth       bool
+	authRequired chan struct{} // auth_required seen, writeLoop sends the token
+	authOK       chan struct{} // closed on auth_ok, writeLoop starts on sendChan
+
+	done     chan struct{} // closed by Shutdown
+	doneOnce sync.Once
+	stopped  chan struct{} // closed when connectLoop returns
+
+	stats *clientMetrics
 	
 	mu            sync.RWMutex      // Protects maps and filter
 	substituteIDs map[string]string // entity_id -> substitute_id
 	idSubstitutes map[string]string // substitute_id -> entity_id
 	filter        []string          // attributes filter
+
+	shadeOverrides map[string]config.ShadeOverride // entity_id -> position mapping
+	artworkBaseURL string                          // bridge HTTP endpoint for entity_picture
+	valueFormats   []config.ValueFormat            // attr_value formatting rules
+
+	pendingMu sync.Mutex
+	pending   map[int64]ResultHandler // request id -> result callback
+
+	statesMu sync.RWMutex
+	states   map[string]EntityState // entity_id -> last known state
+
+	unitMu     sync.RWMutex
+	haTempUnit string // "°C" or "°F", from get_config
+	savantUnit string // "C", "F" or "" (no conversion)
 }
 
+// ResultHandler receives the outcome of a request sent with SendRequest.
+// err is non-nil when Home Assistant answers with success=false.
+type ResultHandler func(result interface{}, err error)
+
 func NewClient(url, token string, onMessage func(string)) *Client {
 	return &Client{
 		url:           url,
@@ -48,9 +84,14 @@ func NewClient(url, token string, onMessage func(string)) *Client {
 		sendChan:      make(chan interface{}, 100),
 		onMessage:     onMessage,
 		reconnectChan: make(chan bool, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		stats:         newClientMetrics(),
 		substituteIDs: make(map[string]string),
 		idSubstitutes: make(map[string]string),
 		filter:        []string{"all"},
+		pending:       make(map[int64]ResultHandler),
+		states:        make(map[string]EntityState),
 	}
 }
 
@@ -79,37 +120,47 @@ func (c *Client) includedWithFilter(key string) bool {
 	return false
 }
 
-func (c *Client) SetSubstituteIDs(subs map[string]string) {
+// SetShadeOverrides installs the per-cover position mappings used to report
+// current_position in Savant's convention.
+func (c *Client) SetShadeOverrides(overrides map[string]config.ShadeOverride) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
-	c.substituteIDs = subs
-	// Rebuild reverse map
-	c.idSubstitutes = make(map[string]string)
-	for k, v := range subs {
-		c.idSubstitutes[v] = k
-	}
+	c.shadeOverrides = overrides
 }
 
-// ResolveID converts a potential substitute ID (from Savant) to a real HA Entity ID
-func (c *Client) ResolveID(id string) string {
+func (c *Client) shadeOverride(entityID string) (config.ShadeOverride, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	
-	if realID, ok := c.idSubstitutes[id]; ok {
-		return realID
-	}
-	return id
+	o, ok := c.shadeOverrides[entityID]
+	return o, ok
+}
+
+// SetArtworkBaseURL makes relative entity_picture values point at the
+// bridge's artwork endpoint. An empty URL leaves them unchanged.
+func (c *Client) SetArtworkBaseURL(baseURL string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.artworkBaseURL = baseURL
 }
 
-func (c *Client) getSubstituteID(entityID string) string {
+// artworkURL rewrites a relative entity_picture path, which needs an HA
+// token, into a URL on the bridge. The path hash changes with the picture
+// so Savant does not keep showing stale artwork.
+func (c *Client) artworkURL(entityID, picture string) string {
 	c.mu.RLock()
-	defer c.mu.RUnlock()
-	
-	if val, ok := c.substituteIDs[entityID]; ok {
-		return val
+	base := c.artworkBaseURL
+	c.mu.RUnlock()
+	if base == "" || !strings.HasPrefix(picture, "/") {
+		return picture
 	}
-	return ""
+	h := fnv.New32a()
+	h.Write([]byte(picture))
+	return fmt.Sprintf("%s/artwork/%s?v=%08x", base, entityID, h.Sum32())
+}
+
+// SetTLSConfig sets the TLS settings used for wss:// URLs.
+func (c *Client) SetTLSConfig(cfg *tls.Config) {
+	c.tlsConfig = cfg
 }
 
 func (c *Client) Start() {
@@ -117,89 +168,176 @@ func (c *Client) Start() {
 }
 
 func (c *Client) connectLoop() {
+	defer close(c.stopped)
 	for {
 		err := c.connect()
 		if err != nil {
 			log.Printf("HA: Connection failed: %v. Retrying in 5s...", err)
-			time.Sleep(5 * time.Second)
-			continue
+			select {
+			case <-time.After(5 * time.Second):
+				continue
+			case <-c.done:
+				return
+			}
 		}
 
 		// Wait for disconnect signal
 		<-c.reconnectChan
-		log.Println("HA: Disconnected, reconnecting...")
 		c.cleanup()
+		c.failPending()
+		if c.shuttingDown() {
+			log.Println("HA: Disconnected")
+			return
+		}
+		log.Println("HA: Disconnected, reconnecting...")
+		c.stats.reconnects.Inc()
 		time.Sleep(1 * time.Second)
 	}
 }
 
 func (c *Client) connect() error {
 	log.Printf("HA: Connecting to %s", c.url)
-	conn, _, err := websocket.DefaultDialer.Dial(c.url, nil)
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = c.tlsConfig
+	conn, _, err := dialer.Dial(c.url, nil)
 	if err != nil {
 		return err
 	}
+	authRequired, authOK := make(chan struct{}, 1), make(chan struct{})
+	c.connMu.Lock()
 	c.conn = conn
 	c.isAuth = false
+	c.authRequired, c.authOK = authRequired, authOK
+	c.connMu.Unlock()
 
-	go c.readLoop()
-	go c.writeLoop()
-	go c.pingLoop()
+	// closed ends the write and ping loops with the connection;
+	// reconnectChan is for connectLoop alone.
+	closed := make(chan struct{})
+	go c.readLoop(conn, closed)
+	go c.writeLoop(conn, closed, authRequired, authOK)
+	go c.pingLoop(closed)
 
 	return nil
 }
 
 func (c *Client) cleanup() {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
 	if c.conn != nil {
 		c.conn.Close()
 		c.conn = nil
 	}
+	c.isAuth = false
 }
 
-func (c *Client) readLoop() {
+// connection returns the current connection, nil while disconnected, and
+// whether it is authenticated.
+func (c *Client) connection() (*websocket.Conn, bool) {
+	c.connMu.RLock()
+	defer c.connMu.RUnlock()
+	return c.conn, c.isAuth
+}
+
+func (c *Client) readLoop(conn *websocket.Conn, closed chan struct{}) {
 	defer func() {
+		close(closed)
 		c.reconnectChan <- true
 	}()
 
 	for {
-		if c.conn == nil {
-			return
-		}
-		_, message, err := c.conn.ReadMessage()
+		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("HA: Read error: %v", err)
+			if !c.shuttingDown() {
+				log.Printf("HA: Read error: %v", err)
+			}
 			return
 		}
 		c.handleMessage(message)
 	}
 }
 
-func (c *Client) writeLoop() {
-	for msg := range c.sendChan {
-		if c.conn == nil {
-			continue
-		}
-		err := c.conn.WriteJSON(msg)
-		if err != nil {
-			log.Printf("HA: Write error: %v", err)
+// writeLoop is the only writer on conn apart from Shutdown's close frame.
+// It sends the token once HA asks for it and leaves sendChan alone until
+// auth_ok, so traffic queued while disconnected waits for the new
+// connection.
+func (c *Client) writeLoop(conn *websocket.Conn, closed <-chan struct{}, authRequired, authOK <-chan struct{}) {
+	select {
+	case <-authRequired:
+	case <-closed:
+		return
+	}
+	err := conn.WriteJSON(map[string]string{
+		"type":         TypeAuth,
+		"access_token": c.token,
+	})
+	if err != nil {
+		log.Printf("HA: Write error: %v", err)
+		return
+	}
+
+	select {
+	case <-authOK:
+	case <-closed:
+		return
+	}
+	for {
+		select {
+		case msg := <-c.sendChan:
+			if err := conn.WriteJSON(msg); err != nil {
+				log.Printf("HA: Write error: %v", err)
+				c.drop(msg)
+				return
+			}
+		case <-closed:
 			return
 		}
 	}
 }
 
-func (c *Client) pingLoop() {
+// enqueue hands msg to the write loop. A full queue drops the message
+// rather than blocking the caller through a long disconnect.
+func (c *Client) enqueue(msg interface{}) {
+	select {
+	case c.sendChan <- msg:
+	default:
+		c.drop(msg)
+	}
+}
+
+// drop counts a message that was never written and fails its pending
+// request, which failPending may already have passed over.
+func (c *Client) drop(msg interface{}) {
+	c.stats.dropped.Inc()
+	cmd, ok := msg.(map[string]interface{})
+	if !ok {
+		return
+	}
+	id, ok := cmd["id"].(int64)
+	if !ok {
+		return
+	}
+	c.pendingMu.Lock()
+	handler, ok := c.pending[id]
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+	if ok && handler != nil {
+		handler(nil, fmt.Errorf("not connected"))
+	}
+}
+
+func (c *Client) pingLoop(closed <-chan struct{}) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			if c.conn != nil && c.isAuth {
+			if conn, auth := c.connection(); conn != nil && auth {
 				c.SendCommand(map[string]interface{}{
 					"type": TypePing,
 				})
 			}
-		case <-c.reconnectChan:
+		case <-closed:
 			return
 		}
 	}
@@ -208,7 +346,79 @@ func (c *Client) pingLoop() {
 func (c *Client) SendCommand(cmd map[string]interface{}) {
 	id := atomic.AddInt64(&c.idCounter, 1)
 	cmd["id"] = id
-	c.sendChan <- cmd
+	switch cmd["type"] {
+	case TypeCallService:
+		c.stats.serviceCalls.Inc()
+		// Tracked without a handler so Shutdown can wait for the result.
+		c.pendingMu.Lock()
+		c.pending[id] = nil
+		c.pendingMu.Unlock()
+	case TypePing:
+		c.stats.pingSent(id)
+	}
+	c.enqueue(cmd)
+}
+
+// SendRequest sends a command and invokes handler with the matching result
+// message. The handler runs on the read loop and must not block.
+func (c *Client) SendRequest(cmd map[string]interface{}, handler ResultHandler) {
+	id := atomic.AddInt64(&c.idCounter, 1)
+	cmd["id"] = id
+	c.pendingMu.Lock()
+	c.pending[id] = handler
+	c.pendingMu.Unlock()
+	c.enqueue(cmd)
+}
+
+func (c *Client) handleResult(msg map[string]interface{}) {
+	idVal, ok := Number(msg["id"])
+	if !ok {
+		return
+	}
+	id := int64(idVal)
+
+	c.pendingMu.Lock()
+	handler, ok := c.pending[id]
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+
+	if success, _ := msg["success"].(bool); !success {
+		errMsg, code := "unknown error", "unknown"
+		if e, ok := msg["error"].(map[string]interface{}); ok {
+			errMsg = fmt.Sprintf("%v: %v", e["code"], e["message"])
+			if s, ok := e["code"].(string); ok && s != "" {
+				code = s
+			}
+		}
+		c.stats.failures.With(code).Inc()
+		err := fmt.Errorf("request %d failed: %s", id, errMsg)
+		if !ok || handler == nil {
+			log.Printf("HA: %v", err)
+			return
+		}
+		handler(nil, err)
+		return
+	}
+	if ok && handler != nil {
+		handler(msg["result"], nil)
+	}
+}
+
+// failPending drops all outstanding requests when the connection is lost.
+func (c *Client) failPending() {
+	c.pendingMu.Lock()
+	pending := c.pending
+	c.pending = make(map[int64]ResultHandler)
+	c.pendingMu.Unlock()
+
+	if len(pending) > 0 {
+		c.stats.failures.With("connection_lost").Add(uint64(len(pending)))
+	}
+	for _, handler := range pending {
+		if handler != nil {
+			handler(nil, fmt.Errorf("connection lost"))
+		}
+	}
 }
 
 func (c *Client) SubscribeEvents() {
@@ -229,7 +439,9 @@ func (c *Client) SubscribeEntities(entityIDs []string) {
 
 func (c *Client) handleMessage(data []byte) {
 	var msg map[string]interface{}
-	if err := json.Unmarshal(data, &msg); err != nil {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&msg); err != nil {
 		log.Printf("HA: JSON decode error: %v", err)
 		return
 	}
@@ -239,22 +451,38 @@ func (c *Client) handleMessage(data []byte) {
 	switch msgType {
 	case TypeAuthRequired:
 		log.Println("HA: Auth required, sending token...")
-		c.conn.WriteJSON(map[string]string{
-			"type":         TypeAuth,
-			"access_token": c.token,
-		})
+		c.connMu.RLock()
+		if c.authRequired != nil {
+			select {
+			case c.authRequired <- struct{}{}:
+			default:
+			}
+		}
+		c.connMu.RUnlock()
 	case TypeAuthOK:
 		log.Println("HA: Auth success!")
+		c.connMu.Lock()
 		c.isAuth = true
+		if c.authOK != nil {
+			close(c.authOK)
+			c.authOK = nil
+		}
+		c.connMu.Unlock()
 		c.SubscribeEvents()
+		c.fetchConfig()
+		c.fetchStates()
 		// Notify Savant we are connected
 		c.onMessage(fmt.Sprintf("hass_websocket_connected,%s\n", time.Now().Format(time.RFC3339)))
+	case TypeAuthInvalid:
+		log.Printf("HA: Auth failed: %v", msg["message"])
+		c.stats.authFailures.Inc()
 	case TypeEvent:
+		c.stats.events.Inc()
 		c.processEvent(msg)
 	case TypeResult:
-		// Handle command results if needed
+		c.handleResult(msg)
 	case TypePong:
-		// Pong received
+		c.stats.pongReceived(msg)
 	default:
 		// log.Printf("HA: Unknown message: %s", msgType)
 	}
@@ -266,6 +494,9 @@ func (c *Client) processEvent(msg map[string]interface{}) {
 	if !ok {
 		return
 	}
+	// subscribe_entities updates carry no event_type and are ignored: the
+	// same changes arrive as state_changed through subscribe_events, which
+	// is always sent on auth.
 	eventType, _ := event["event_type"].(string)
 
 	if eventType == "state_changed" {
@@ -275,8 +506,12 @@ func (c *Client) processEvent(msg map[string]interface{}) {
 		}
 		newState, ok := data["new_state"].(map[string]interface{})
 		if !ok || newState == nil {
+			if entityID, _ := data["entity_id"].(string); entityID != "" {
+				c.forgetState(entityID)
+			}
 			return
 		}
+		c.cacheState(newState)
 
 		c.flattenAndSend(newState, []string{})
 	} else if eventType == "call_service" {
@@ -316,18 +551,28 @@ func (c *Client) parseService(data map[string]interface{}) {
 
 	for _, entity := range entities {
 		// "type:call_service,entity:#{entity},service:#{data['service']},domain:#{data['domain']}"
-		msg := fmt.Sprintf("type:call_service,entity:%s,service:%s,domain:%s\n", entity, service, domain)
+		msg := fmt.Sprintf("type:call_service,entity:%s,service:%s,domain:%s\n", c.OutboundID(entity), service, domain)
 		c.onMessage(msg)
 	}
 }
 
+// sendLockState reports lock state transitions as their own line so that
+// Savant can show jammed/locking/unlocking without parsing attributes.
+func (c *Client) sendLockState(entityID, state string) {
+	if !strings.HasPrefix(entityID, "lock.") {
+		return
+	}
+	c.onMessage(fmt.Sprintf("type:lock_state,entity:%s,state:%s\n", c.OutboundID(entityID), state))
+}
+
 // flattenAndSend recursively flattens the JSON and sends formatted strings
 func (c *Client) flattenAndSend(data map[string]interface{}, parents []string) {
 	entityID, _ := data["entity_id"].(string)
+	subID := c.getSubstituteID(entityID)
 	
 	// Handle State
 	if state, ok := data["state"]; ok {
-		c.sendSavantUpdate(entityID, parents, "state", state)
+		c.sendSavantUpdate(entityID, subID, parents, "state", state)
 	}
 
 	// Handle Attributes
@@ -336,31 +581,41 @@ func (c *Client) flattenAndSend(data map[string]interface{}, parents []string) {
 		newParents := append(parents, "attributes")
 		
 		// Recursively handle attributes
-		c.processMap(entityID, attrs, newParents)
+		c.processMap(entityID, subID, attrs, newParents)
 	}
 }
 
-func (c *Client) processMap(entityID string, data map[string]interface{}, parents []string) {
+func (c *Client) processMap(entityID, subID string, data map[string]interface{}, parents []string) {
 	var mergedAttrs []string
 
-	for k, v := range data {
+	// Sorted so that the output order is stable
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := data[k]
 		// Ruby: "#{k}:#{v}" - collects all keys regardless of filter
-		mergedAttrs = append(mergedAttrs, fmt.Sprintf("%s:%v", k, v))
+		mergedAttrs = append(mergedAttrs, fmt.Sprintf("%s:%s", k, rubyString(v)))
 
 		if !c.includedWithFilter(k) {
 			continue
 		}
 		switch val := v.(type) {
 		case map[string]interface{}:
-			c.processMap(entityID, val, append(parents, k))
+			c.processMap(entityID, subID, val, appendPath(parents, k))
 		case []interface{}:
-			strs := make([]string, len(val))
-			for i, item := range val {
-				strs[i] = fmt.Sprintf("%v", item)
+			// Ruby sends arrays inside a hash as their inspect string;
+			// only arrays of hashes are expanded.
+			if hashedArray(val) {
+				c.processHashedArray(entityID, subID, val, appendPath(parents, k))
+			} else {
+				c.sendSavantUpdate(entityID, subID, appendPath(parents, k), k, rubyInspect(val))
 			}
-			c.sendSavantUpdate(entityID, append(parents, k), k, strings.Join(strs, ","))
 		default:
-			c.sendSavantUpdate(entityID, append(parents, k), k, val)
+			c.sendSavantUpdate(entityID, subID, appendPath(parents, k), k, val)
 		}
 	}
 
@@ -386,32 +641,111 @@ func (c *Client) processMap(entityID string, data map[string]interface{}, parent
 		// So it adds ANOTHER 'attributes' level?
 		// Yes.
 		
-		c.sendSavantUpdate(entityID, append(parents, "attributes"), entityID, strings.Join(mergedAttrs, ","))
+		c.sendSavantUpdate(entityID, subID, appendPath(parents, "attributes"), entityID, strings.Join(mergedAttrs, ","))
 	}
 }
 
-func (c *Client) sendSavantUpdate(entityID string, parents []string, attrName string, value interface{}) {
-	if value == nil || !c.includedWithFilter(attrName) {
+// processArray mirrors Ruby's update_with_array: arrays of objects are
+// expanded element by element, anything else is sent joined with commas.
+func (c *Client) processArray(entityID, subID, attrName string, arr []interface{}, parents []string) {
+	if hashedArray(arr) {
+		c.processHashedArray(entityID, subID, arr, parents)
+		return
+	}
+	c.sendSavantUpdate(entityID, subID, parents, attrName, rubyJoin(arr, ","))
+}
+
+// hashedArray reports whether Ruby would expand arr element by element,
+// which it decides by the first element alone.
+func hashedArray(arr []interface{}) bool {
+	if len(arr) == 0 {
+		return false
+	}
+	_, ok := arr[0].(map[string]interface{})
+	return ok
+}
+
+// processHashedArray mirrors Ruby's update_hashed_array. Element i is sent
+// under the entity "<parentKey>_<i>" with i appended to the parent keys, so
+// forecast[0].temperature of weather.home becomes
+// entity_id=weather.home_0&parent_keys=attributes_forecast_0_temperature,
+// and a substitute ID "home" becomes substitute_id=home_0.
+func (c *Client) processHashedArray(parentKey, parentSub string, arr []interface{}, parents []string) {
+	for i, e := range arr {
+		newKey := fmt.Sprintf("%s_%d", parentKey, i)
+		newSub := ""
+		if parentSub != "" {
+			newSub = fmt.Sprintf("%s_%d", parentSub, i)
+		}
+		index := strconv.Itoa(i)
+		nextParents := appendPath(parents, index)
+
+		switch val := e.(type) {
+		case map[string]interface{}:
+			c.processMap(newKey, newSub, val, nextParents)
+		case []interface{}:
+			// Ruby uses the parent key as attr_name for nested arrays
+			c.processArray(newKey, newSub, newKey, val, nextParents)
+		default:
+			c.sendSavantUpdate(newKey, newSub, nextParents, index, val)
+		}
+	}
+}
+
+// appendPath returns parents+key without sharing the backing array, so
+// sibling branches of the recursion cannot overwrite each other.
+func appendPath(parents []string, key string) []string {
+	out := make([]string, len(parents), len(parents)+1)
+	copy(out, parents)
+	return append(out, key)
+}
+
+func (c *Client) sendSavantUpdate(entityID, subID string, parents []string, attrName string, value interface{}) {
+	// Ruby: return unless value, so false is dropped like nil
+	if value == nil || value == false || !c.includedWithFilter(attrName) {
 		return
 	}
 	
 	// Hack for brightness (from Ruby code)
 	// value = 3 if attr_name == 'brightness' && [1, 2].include?(value)
 	if attrName == "brightness" {
-		if vInt, ok := value.(float64); ok { // JSON numbers are floats
+		if vInt, ok := Number(value); ok {
 			if vInt == 1 || vInt == 2 {
 				value = 3
 			}
 		}
 	}
 
+	if c.isTemperatureAttr(entityID, attrName) {
+		// Only converted values are replaced, so the rest keep their
+		// formatting.
+		if temp, ok := Number(value); ok {
+			if convert, _ := c.conversion(); convert {
+				value = c.ToSavantTemperature(temp)
+			}
+		}
+	}
+
+	if attrName == "entity_picture" {
+		if picture, ok := value.(string); ok {
+			value = c.artworkURL(entityID, picture)
+		}
+	}
+
+	if attrName == "current_position" {
+		if pos, ok := Number(value); ok {
+			if o, found := c.shadeOverride(entityID); found {
+				value = o.ToSavant(int(pos))
+			}
+		}
+	}
+
 	joinedParents := strings.Join(parents, "_")
 	
 	// Format: entity_id=...&substitute_id=...&parent_keys=...&attr_name=...&attr_value=...
-	subID := c.getSubstituteID(entityID)
 	
-	output := fmt.Sprintf("entity_id=%s&substitute_id=%s&parent_keys=%s&attr_name=%s&attr_value=%v\n",
-		entityID, subID, joinedParents, attrName, value)
+	output := fmt.Sprintf("entity_id=%s&substitute_id=%s&parent_keys=%s&attr_name=%s&attr_value=%s\n",
+		entityID, subID, joinedParents, attrName, c.formatValue(entityID, attrName, value))
 	
 	c.onMessage(output)
 }
@@ -0,0 +1,104 @@
+package ha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// fakeHA accepts each connection, asks for auth, and hands the frames the
+// bridge writes to the test.
+type fakeHA struct {
+	conns  chan *websocket.Conn
+	frames chan map[string]interface{}
+}
+
+func newFakeHA(t *testing.T) (*fakeHA, string) {
+	t.Helper()
+	f := &fakeHA{conns: make(chan *websocket.Conn, 4), frames: make(chan map[string]interface{}, 100)}
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.WriteJSON(map[string]string{"type": TypeAuthRequired})
+		f.conns <- conn
+		for {
+			var frame map[string]interface{}
+			if err := conn.ReadJSON(&frame); err != nil {
+				return
+			}
+			f.frames <- frame
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return f, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func (f *fakeHA) next(t *testing.T) map[string]interface{} {
+	t.Helper()
+	select {
+	case frame := <-f.frames:
+		return frame
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame from the bridge")
+		return nil
+	}
+}
+
+func (f *fakeHA) accept(t *testing.T) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-f.conns:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("bridge did not connect")
+		return nil
+	}
+}
+
+func TestAuthIsWrittenBeforeQueuedTraffic(t *testing.T) {
+	f, url := newFakeHA(t)
+	c := NewClient(url, "secret", func(string) {})
+
+	// Queued while disconnected, so it has to wait for auth_ok.
+	c.SendCommand(map[string]interface{}{"type": TypeCallService, "domain": "light", "service": "turn_on"})
+	c.Start()
+
+	for round := 0; round < 2; round++ {
+		conn := f.accept(t)
+		if frame := f.next(t); frame["type"] != TypeAuth || frame["access_token"] != "secret" {
+			t.Fatalf("round %d: first frame = %v, want auth", round, frame)
+		}
+		select {
+		case frame := <-f.frames:
+			t.Fatalf("round %d: %v written before auth_ok", round, frame)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		conn.WriteJSON(map[string]string{"type": TypeAuthOK})
+		want := []string{TypeCallService, "subscribe_events", "get_config", "get_states"}
+		if round > 0 {
+			want = want[1:]
+		}
+		for _, typ := range want {
+			if frame := f.next(t); frame["type"] != typ {
+				t.Fatalf("round %d: frame = %v, want %s", round, frame, typ)
+			}
+		}
+
+		// Dropping the connection makes the bridge reconnect with a
+		// fresh write loop.
+		conn.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	c.Shutdown(ctx)
+}
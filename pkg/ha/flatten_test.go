@@ -0,0 +1,46 @@
+package ha
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The golden files hold the lines the Ruby bridge (hass_savant.rb.bak)
+// sends for the same state_changed event: false and nil values are
+// dropped, arrays inside a hash are sent as their inspect string unless
+// they hold hashes, and hashes are inspected in the Ruby 3.3 form,
+// {"k"=>"v"}. The attributes in the inputs are in alphabetical order, the
+// order the Go bridge sends them in, so the merged "attributes" line
+// matches Ruby's insertion order.
+func TestFlattenGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inputs) == 0 {
+		t.Fatal("no golden inputs in testdata")
+	}
+	for _, input := range inputs {
+		name := strings.TrimSuffix(filepath.Base(input), ".json")
+		t.Run(name, func(t *testing.T) {
+			event, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(strings.TrimSuffix(input, ".json") + ".golden")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got strings.Builder
+			c := NewClient("", "", func(line string) { got.WriteString(line) })
+			c.handleMessage(event)
+
+			if got.String() != string(want) {
+				t.Errorf("output differs from %s.golden\ngot:\n%s\nwant:\n%s", name, got.String(), want)
+			}
+		})
+	}
+}
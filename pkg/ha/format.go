@@ -0,0 +1,157 @@
+package ha
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+)
+
+// unitFactors maps units of the same dimension to their factor relative
+// to the dimension's base unit.
+var unitFactors = []map[string]float64{
+	{"W": 1, "kW": 1e3, "MW": 1e6},
+	{"Wh": 1, "kWh": 1e3, "MWh": 1e6},
+	{"Pa": 1, "hPa": 100, "kPa": 1e3, "bar": 1e5, "mbar": 100, "psi": 6894.757, "inHg": 3386.389, "mmHg": 133.322},
+	{"m/s": 1, "km/h": 1 / 3.6, "mph": 0.44704, "kn": 0.514444},
+	{"L": 1, "mL": 1e-3, "m³": 1e3, "gal": 3.785411, "ft³": 28.316847},
+	{"mm": 1e-3, "cm": 1e-2, "m": 1, "km": 1e3, "in": 0.0254, "ft": 0.3048, "mi": 1609.344},
+	{"V": 1, "mV": 1e-3, "kV": 1e3},
+	{"A": 1, "mA": 1e-3},
+}
+
+// SetValueFormats installs the formatting rules applied to outgoing values.
+func (c *Client) SetValueFormats(rules []config.ValueFormat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.valueFormats = rules
+}
+
+// formatValue applies the matching rule to a value and renders it as the
+// string written after attr_value=.
+func (c *Client) formatValue(entityID, attrName string, value interface{}) string {
+	c.mu.RLock()
+	rules := c.valueFormats
+	c.mu.RUnlock()
+
+	if len(rules) > 0 {
+		st, _ := c.Entity(entityID)
+		deviceClass, _ := st.Attributes["device_class"].(string)
+
+		// Report the converted unit alongside a converted state
+		if attrName == "unit_of_measurement" {
+			if rule, ok := config.FindValueFormat(rules, entityID, deviceClass, "state"); ok && rule.Unit != "" {
+				return rule.Unit
+			}
+		}
+
+		if rule, ok := config.FindValueFormat(rules, entityID, deviceClass, attrName); ok {
+			if v, ok := toFloat(value); ok {
+				fromUnit := rule.FromUnit
+				if fromUnit == "" {
+					fromUnit, _ = st.Attributes["unit_of_measurement"].(string)
+				}
+				if rule.Unit != "" && fromUnit != "" {
+					if converted, ok := convertUnit(v, fromUnit, rule.Unit); ok {
+						v = converted
+					}
+				}
+				decimals := -1
+				if rule.Decimals != nil {
+					decimals = *rule.Decimals
+				}
+				return strconv.FormatFloat(v, 'f', decimals, 64)
+			}
+		}
+	}
+	return formatScalar(value)
+}
+
+// formatScalar renders a value like Ruby's to_s, except that floats never
+// use scientific notation (1234567.8 instead of 1.2345678e+06).
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case json.Number:
+		return formatNumber(v)
+	case float64:
+		return formatFloat(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// formatNumber renders a number from an HA message: integers as sent,
+// anything with a fraction or exponent as a float, so 21 stays "21" and
+// 21.0 stays "21.0".
+func formatNumber(n json.Number) string {
+	if !strings.ContainsAny(string(n), ".eE") {
+		return string(n)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return string(n)
+	}
+	return formatFloat(f)
+}
+
+// formatFloat renders a float like Ruby's Float#to_s: whole numbers keep
+// their ".0".
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		s += ".0"
+	}
+	return s
+}
+
+// Number returns the value of a JSON number. Messages from HA are decoded
+// with json.Number so that formatNumber can tell 21 from 21.0.
+func Number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if f, ok := Number(value); ok {
+		return f, true
+	}
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+func convertUnit(v float64, from, to string) (float64, bool) {
+	if from == to {
+		return v, true
+	}
+	switch {
+	case isCelsius(from) && isFahrenheit(to):
+		return v*9/5 + 32, true
+	case isFahrenheit(from) && isCelsius(to):
+		return (v - 32) * 5 / 9, true
+	}
+	for _, dim := range unitFactors {
+		f1, ok1 := dim[from]
+		f2, ok2 := dim[to]
+		if ok1 && ok2 {
+			return v * f1 / f2, true
+		}
+	}
+	return v, false
+}
+
+func isCelsius(u string) bool    { return u == "°C" || u == "C" }
+func isFahrenheit(u string) bool { return u == "°F" || u == "F" }
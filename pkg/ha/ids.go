@@ -0,0 +1,81 @@
+package ha
+
+import "strings"
+
+// Substitute IDs let a Savant profile address an entity by a short name
+// of its own choosing (sent with the substitute_ids command). This file is
+// the single place where IDs are translated between the two namespaces:
+//
+//   - Inbound, ResolveID and ResolveList map a substitute back to the real
+//     HA entity_id before a service is called.
+//   - Outbound, OutboundID maps an entity_id to the substitute on every
+//     line that names an entity in an "entity:"-style field (call_service
+//     events, lock_state, browse_media, camera_snapshot). State lines keep
+//     the real entity_id and carry the substitute in substitute_id, which
+//     the profiles already match on (hass_alarm.xml keys on
+//     entity_id=alarm_control_panel. and substitute_id=partition), so the
+//     Ruby bridge's swap of entity_id itself is deliberately not repeated.
+//
+// IDs without a substitute pass through unchanged in both directions.
+
+// SetSubstituteIDs merges entity_id -> substitute_id pairs into the table.
+// Savant sends substitute_ids once per profile, so later calls extend the
+// table instead of replacing it. An empty substitute removes the mapping.
+func (c *Client) SetSubstituteIDs(subs map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for entityID, subID := range subs {
+		if old, ok := c.substituteIDs[entityID]; ok {
+			delete(c.idSubstitutes, old)
+		}
+		if subID == "" {
+			delete(c.substituteIDs, entityID)
+			continue
+		}
+		c.substituteIDs[entityID] = subID
+		c.idSubstitutes[subID] = entityID
+	}
+}
+
+// ResolveID converts a potential substitute ID (from Savant) to a real HA Entity ID
+func (c *Client) ResolveID(id string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if realID, ok := c.idSubstitutes[id]; ok {
+		return realID
+	}
+	return id
+}
+
+// ResolveList resolves each element of a "|"-separated entity list.
+func (c *Client) ResolveList(ids string) string {
+	if !strings.Contains(ids, "|") {
+		return c.ResolveID(ids)
+	}
+	parts := strings.Split(ids, "|")
+	for i, id := range parts {
+		parts[i] = c.ResolveID(strings.TrimSpace(id))
+	}
+	return strings.Join(parts, "|")
+}
+
+// OutboundID returns the ID Savant knows an entity by: its substitute if
+// one is registered, otherwise the entity_id itself.
+func (c *Client) OutboundID(entityID string) string {
+	if sub := c.getSubstituteID(entityID); sub != "" {
+		return sub
+	}
+	return entityID
+}
+
+func (c *Client) getSubstituteID(entityID string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if val, ok := c.substituteIDs[entityID]; ok {
+		return val
+	}
+	return ""
+}
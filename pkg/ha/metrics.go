@@ -0,0 +1,64 @@
+package ha
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/metrics"
+)
+
+// clientMetrics are the instruments a Client updates as it runs.
+type clientMetrics struct {
+	serviceCalls metrics.Counter
+	failures     *metrics.CounterVec // by HA error code
+	reconnects   metrics.Counter
+	authFailures metrics.Counter
+	events       metrics.Counter
+	dropped      metrics.Counter
+	pongLatency  *metrics.Histogram
+
+	pingMu sync.Mutex
+	pingID int64 // last ping sent, 0 once answered
+	pingAt time.Time
+}
+
+func newClientMetrics() *clientMetrics {
+	return &clientMetrics{
+		failures:    metrics.NewCounterVec("code"),
+		pongLatency: metrics.NewHistogram(0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5),
+	}
+}
+
+// RegisterMetrics adds the client's instruments to r.
+func (c *Client) RegisterMetrics(r *metrics.Registry) {
+	r.Register("bridge_ha_service_calls_total", "Service calls sent to Home Assistant.", &c.stats.serviceCalls)
+	r.Register("bridge_ha_request_failures_total", "Requests Home Assistant answered with an error, by error code.", c.stats.failures)
+	r.Register("bridge_ha_reconnects_total", "Reconnects after the Home Assistant connection was lost.", &c.stats.reconnects)
+	r.Register("bridge_ha_auth_failures_total", "Rejected authentication attempts.", &c.stats.authFailures)
+	r.Register("bridge_ha_events_total", "Events received from Home Assistant.", &c.stats.events)
+	r.Register("bridge_ha_dropped_messages_total", "Messages to Home Assistant dropped while disconnected or on write errors.", &c.stats.dropped)
+	r.Register("bridge_ha_send_queue_length", "Messages waiting to be written to Home Assistant.", metrics.GaugeFunc(func() float64 {
+		return float64(len(c.sendChan))
+	}))
+	r.Register("bridge_ha_pending_requests", "Requests waiting for a result from Home Assistant.", metrics.GaugeFunc(func() float64 {
+		return float64(c.pendingCount())
+	}))
+	r.Register("bridge_ha_pong_latency_seconds", "Round-trip time of websocket pings.", c.stats.pongLatency)
+}
+
+func (m *clientMetrics) pingSent(id int64) {
+	m.pingMu.Lock()
+	defer m.pingMu.Unlock()
+	m.pingID, m.pingAt = id, time.Now()
+}
+
+func (m *clientMetrics) pongReceived(msg map[string]interface{}) {
+	id, _ := Number(msg["id"])
+	m.pingMu.Lock()
+	defer m.pingMu.Unlock()
+	if m.pingID == 0 || int64(id) != m.pingID {
+		return
+	}
+	m.pongLatency.Observe(time.Since(m.pingAt).Seconds())
+	m.pingID = 0
+}
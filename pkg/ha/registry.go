@@ -0,0 +1,212 @@
+package ha
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Area is an entry of the HA area registry.
+type Area struct {
+	ID      string
+	Name    string
+	FloorID string
+}
+
+// Device is an entry of the HA device registry.
+type Device struct {
+	ID           string
+	Name         string
+	AreaID       string
+	Manufacturer string
+	Model        string
+}
+
+// RegistryEntity describes an entity for export to Savant. AreaID falls
+// back to the device's area, and Name to the friendly_name of the state.
+type RegistryEntity struct {
+	EntityID          string
+	Name              string
+	AreaID            string
+	DeviceID          string
+	SupportedFeatures int
+}
+
+// ListAreas fetches the area registry, sorted by name.
+func (c *Client) ListAreas(handler func([]Area, error)) {
+	c.SendRequest(map[string]interface{}{"type": "config/area_registry/list"}, func(result interface{}, err error) {
+		if err != nil {
+			handler(nil, err)
+			return
+		}
+		var areas []Area
+		for _, item := range registryItems(result) {
+			areas = append(areas, Area{
+				ID:      registryString(item, "area_id"),
+				Name:    registryString(item, "name"),
+				FloorID: registryString(item, "floor_id"),
+			})
+		}
+		sort.Slice(areas, func(i, j int) bool { return areas[i].Name < areas[j].Name })
+		handler(areas, nil)
+	})
+}
+
+// ListDevices fetches the device registry, sorted by name. Disabled
+// devices are left out.
+func (c *Client) ListDevices(handler func([]Device, error)) {
+	c.SendRequest(map[string]interface{}{"type": "config/device_registry/list"}, func(result interface{}, err error) {
+		if err != nil {
+			handler(nil, err)
+			return
+		}
+		var devices []Device
+		for _, item := range registryItems(result) {
+			if item["disabled_by"] != nil {
+				continue
+			}
+			name := registryString(item, "name_by_user")
+			if name == "" {
+				name = registryString(item, "name")
+			}
+			devices = append(devices, Device{
+				ID:           registryString(item, "id"),
+				Name:         name,
+				AreaID:       registryString(item, "area_id"),
+				Manufacturer: registryString(item, "manufacturer"),
+				Model:        registryString(item, "model"),
+			})
+		}
+		sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+		handler(devices, nil)
+	})
+}
+
+// ListEntities fetches the entity registry, optionally limited to one
+// domain, sorted by entity ID. Entities HA knows only by state (no unique
+// ID, so not in the registry) are included from the state cache. Disabled
+// entities are left out.
+func (c *Client) ListEntities(domain string, handler func([]RegistryEntity, error)) {
+	// The device registry supplies areas inherited from devices. Both
+	// requests go out here: result handlers run on the read loop and must
+	// not send requests themselves.
+	var (
+		mu      sync.Mutex
+		entries []map[string]interface{}
+		devices []Device
+		failed  error
+		pending = 2
+	)
+	finish := func(err error) {
+		mu.Lock()
+		if err != nil && failed == nil {
+			failed = err
+		}
+		pending--
+		last := pending == 0
+		mu.Unlock()
+		if !last {
+			return
+		}
+		if failed != nil {
+			handler(nil, failed)
+			return
+		}
+		handler(c.joinEntities(domain, entries, devices), nil)
+	}
+
+	c.ListDevices(func(d []Device, err error) {
+		mu.Lock()
+		devices = d
+		mu.Unlock()
+		finish(err)
+	})
+	c.SendRequest(map[string]interface{}{"type": "config/entity_registry/list"}, func(result interface{}, err error) {
+		mu.Lock()
+		entries = registryItems(result)
+		mu.Unlock()
+		finish(err)
+	})
+}
+
+// joinEntities builds the entity list of ListEntities from the entity and
+// device registries and the state cache.
+func (c *Client) joinEntities(domain string, entries []map[string]interface{}, devices []Device) []RegistryEntity {
+	deviceAreas := make(map[string]string, len(devices))
+	for _, d := range devices {
+		deviceAreas[d.ID] = d.AreaID
+	}
+
+	seen := make(map[string]bool)
+	var entities []RegistryEntity
+	for _, item := range entries {
+		e := RegistryEntity{
+			EntityID: registryString(item, "entity_id"),
+			AreaID:   registryString(item, "area_id"),
+			DeviceID: registryString(item, "device_id"),
+		}
+		seen[e.EntityID] = true
+		if item["disabled_by"] != nil || !inDomain(e.EntityID, domain) {
+			continue
+		}
+		if e.AreaID == "" {
+			e.AreaID = deviceAreas[e.DeviceID]
+		}
+		e.Name = registryString(item, "name")
+		if e.Name == "" {
+			e.Name = registryString(item, "original_name")
+		}
+		entities = append(entities, c.withState(e))
+	}
+
+	c.statesMu.RLock()
+	var stateOnly []string
+	for id := range c.states {
+		if !seen[id] && inDomain(id, domain) {
+			stateOnly = append(stateOnly, id)
+		}
+	}
+	c.statesMu.RUnlock()
+	for _, id := range stateOnly {
+		entities = append(entities, c.withState(RegistryEntity{EntityID: id}))
+	}
+
+	sort.Slice(entities, func(i, j int) bool { return entities[i].EntityID < entities[j].EntityID })
+	return entities
+}
+
+// withState fills in the friendly name and supported features from the
+// cached state of an entity.
+func (c *Client) withState(e RegistryEntity) RegistryEntity {
+	st, ok := c.Entity(e.EntityID)
+	if !ok {
+		return e
+	}
+	if name, _ := st.Attributes["friendly_name"].(string); name != "" {
+		e.Name = name
+	}
+	if f, ok := Number(st.Attributes["supported_features"]); ok {
+		e.SupportedFeatures = int(f)
+	}
+	return e
+}
+
+func inDomain(entityID, domain string) bool {
+	return domain == "" || strings.HasPrefix(entityID, domain+".")
+}
+
+func registryItems(result interface{}) []map[string]interface{} {
+	list, _ := result.([]interface{})
+	items := make([]map[string]interface{}, 0, len(list))
+	for _, v := range list {
+		if item, ok := v.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func registryString(item map[string]interface{}, key string) string {
+	s, _ := item[key].(string)
+	return s
+}
@@ -0,0 +1,66 @@
+package ha
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The merged "attributes" line is built the way the Ruby bridge did with
+// "#{k}:#{v}", so profiles written against it keep parsing the same text.
+
+// rubyString renders a value like Ruby's to_s.
+func rubyString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		return rubyInspect(v)
+	}
+}
+
+// rubyJoin joins an array like Ruby's Array#join: nested arrays are joined
+// into the same list, nil elements are empty.
+func rubyJoin(arr []interface{}, sep string) string {
+	parts := make([]string, len(arr))
+	for i, e := range arr {
+		if nested, ok := e.([]interface{}); ok {
+			parts[i] = rubyJoin(nested, sep)
+			continue
+		}
+		parts[i] = rubyString(e)
+	}
+	return strings.Join(parts, sep)
+}
+
+// rubyInspect renders a value like Ruby's inspect, which is what to_s
+// gives for arrays and hashes: ["a", 1] and {"k"=>"v"}.
+func rubyInspect(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q", val)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, e := range val {
+			parts[i] = rubyInspect(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%q=>%s", k, rubyInspect(val[k]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	default:
+		return formatScalar(v)
+	}
+}
@@ -0,0 +1,74 @@
+package ha
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Shutdown stops reconnecting, waits for queued messages to be written and
+// for pending service calls to be answered, then closes the connection with
+// a close frame. It stops waiting when ctx is done.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.doneOnce.Do(func() { close(c.done) })
+
+	err := c.drain(ctx)
+
+	if conn, _ := c.connection(); conn != nil {
+		msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bridge shutting down")
+		if werr := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second)); werr != nil {
+			log.Printf("HA: Close error: %v", werr)
+			conn.Close()
+		}
+	}
+
+	// connectLoop returns once the read loop sees HA's close reply.
+	select {
+	case <-c.stopped:
+	case <-ctx.Done():
+		c.cleanup()
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
+
+func (c *Client) shuttingDown() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// drain waits until sendChan is empty and no requests are pending. Nothing
+// can be flushed while disconnected, so it then only reports what is lost.
+func (c *Client) drain(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		queued, pending := len(c.sendChan), c.pendingCount()
+		if queued == 0 && pending == 0 {
+			return nil
+		}
+		if conn, auth := c.connection(); conn == nil || !auth {
+			return fmt.Errorf("not connected, dropping %d queued messages and %d pending requests", queued, pending)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("%v with %d queued messages and %d pending requests", ctx.Err(), queued, pending)
+		}
+	}
+}
+
+func (c *Client) pendingCount() int {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	return len(c.pending)
+}
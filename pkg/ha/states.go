@@ -0,0 +1,63 @@
+package ha
+
+import "log"
+
+// EntityState is the last known state of an entity, kept so that commands
+// can be checked against attributes such as code_format or options.
+type EntityState struct {
+	State      string
+	Attributes map[string]interface{}
+}
+
+// Entity returns the cached state of an entity.
+func (c *Client) Entity(entityID string) (EntityState, bool) {
+	c.statesMu.RLock()
+	defer c.statesMu.RUnlock()
+	st, ok := c.states[entityID]
+	return st, ok
+}
+
+// fetchStates seeds the cache with every entity after authentication.
+func (c *Client) fetchStates() {
+	c.SendRequest(map[string]interface{}{"type": "get_states"}, func(result interface{}, err error) {
+		if err != nil {
+			log.Printf("HA: get_states failed: %v", err)
+			return
+		}
+		list, _ := result.([]interface{})
+		for _, item := range list {
+			if st, ok := item.(map[string]interface{}); ok {
+				c.cacheState(st)
+			}
+		}
+		log.Printf("HA: Cached %d entity states", len(list))
+	})
+}
+
+// cacheState records an HA state object (entity_id, state, attributes).
+// Every state update goes through here, so lock transitions are reported
+// from here too, including the current state of each lock when the cache
+// is seeded.
+func (c *Client) cacheState(st map[string]interface{}) {
+	entityID, _ := st["entity_id"].(string)
+	if entityID == "" {
+		return
+	}
+	state, _ := st["state"].(string)
+	attrs, _ := st["attributes"].(map[string]interface{})
+
+	c.statesMu.Lock()
+	prev, known := c.states[entityID]
+	c.states[entityID] = EntityState{State: state, Attributes: attrs}
+	c.statesMu.Unlock()
+
+	if !known || prev.State != state {
+		c.sendLockState(entityID, state)
+	}
+}
+
+func (c *Client) forgetState(entityID string) {
+	c.statesMu.Lock()
+	delete(c.states, entityID)
+	c.statesMu.Unlock()
+}
@@ -0,0 +1,39 @@
+package ha
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLockStateTransitions(t *testing.T) {
+	var lines []string
+	c := NewClient("", "", func(line string) {
+		if strings.HasPrefix(line, "type:lock_state,") {
+			lines = append(lines, line)
+		}
+	})
+	c.SetSubstituteIDs(map[string]string{"lock.front": "front"})
+
+	lockEvent := func(state string) []byte {
+		return []byte(`{"type": "event", "event": {"event_type": "state_changed", "data": {"entity_id": "lock.front",` +
+			`"new_state": {"entity_id": "lock.front", "state": "` + state + `", "attributes": {}}}}}`)
+	}
+
+	// Seeding the cache reports the current state of each lock.
+	c.cacheState(map[string]interface{}{"entity_id": "lock.front", "state": "locked"})
+	c.cacheState(map[string]interface{}{"entity_id": "light.hall", "state": "on"})
+	c.handleMessage(lockEvent("locked"))
+	c.handleMessage(lockEvent("unlocking"))
+	c.handleMessage(lockEvent("jammed"))
+	c.handleMessage(lockEvent("jammed"))
+
+	want := []string{
+		"type:lock_state,entity:front,state:locked\n",
+		"type:lock_state,entity:front,state:unlocking\n",
+		"type:lock_state,entity:front,state:jammed\n",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lock_state lines = %q, want %q", lines, want)
+	}
+}
@@ -0,0 +1,95 @@
+package ha
+
+import (
+	"log"
+	"math"
+	"strings"
+)
+
+// temperatureAttrs are the climate/water_heater attributes that carry a
+// temperature in the HA unit system.
+var temperatureAttrs = map[string]bool{
+	"temperature":         true,
+	"current_temperature": true,
+	"target_temp_high":    true,
+	"target_temp_low":     true,
+	"min_temp":            true,
+	"max_temp":            true,
+}
+
+// SetSavantTemperatureUnit selects the unit Savant thermostats use ("C" or
+// "F"). An empty unit disables conversion.
+func (c *Client) SetSavantTemperatureUnit(unit string) {
+	c.unitMu.Lock()
+	defer c.unitMu.Unlock()
+	c.savantUnit = strings.ToUpper(unit)
+}
+
+// fetchConfig asks HA for its unit system so temperatures can be converted.
+func (c *Client) fetchConfig() {
+	c.SendRequest(map[string]interface{}{"type": "get_config"}, func(result interface{}, err error) {
+		if err != nil {
+			log.Printf("HA: get_config failed: %v", err)
+			return
+		}
+		cfg, _ := result.(map[string]interface{})
+		units, _ := cfg["unit_system"].(map[string]interface{})
+		unit, _ := units["temperature"].(string)
+		if unit == "" {
+			return
+		}
+		c.unitMu.Lock()
+		c.haTempUnit = unit
+		c.unitMu.Unlock()
+		log.Printf("HA: Temperature unit is %s", unit)
+	})
+}
+
+// conversion reports whether temperatures must be converted and in which
+// direction: toF is true when Savant uses Fahrenheit and HA uses Celsius.
+func (c *Client) conversion() (convert, toF bool) {
+	c.unitMu.RLock()
+	defer c.unitMu.RUnlock()
+	if c.savantUnit == "" || c.haTempUnit == "" {
+		return false, false
+	}
+	haF := strings.HasSuffix(c.haTempUnit, "F")
+	savantF := c.savantUnit == "F"
+	return haF != savantF, savantF
+}
+
+// ToHATemperature converts a temperature sent by Savant to the HA unit.
+func (c *Client) ToHATemperature(v float64) float64 {
+	convert, savantF := c.conversion()
+	if !convert {
+		return v
+	}
+	if savantF {
+		return roundHalf((v - 32) * 5 / 9)
+	}
+	return math.Round(v*9/5 + 32)
+}
+
+// ToSavantTemperature converts an HA temperature to the Savant unit.
+func (c *Client) ToSavantTemperature(v float64) float64 {
+	convert, savantF := c.conversion()
+	if !convert {
+		return v
+	}
+	if savantF {
+		return math.Round(v*9/5 + 32)
+	}
+	return roundHalf((v - 32) * 5 / 9)
+}
+
+func (c *Client) isTemperatureAttr(entityID, attrName string) bool {
+	if !temperatureAttrs[attrName] {
+		return false
+	}
+	return strings.HasPrefix(entityID, "climate.") || strings.HasPrefix(entityID, "water_heater.")
+}
+
+// roundHalf rounds Celsius values to the 0.5 steps thermostats accept.
+func roundHalf(v float64) float64 {
+	return math.Round(v*2) / 2
+}
@@ -0,0 +1,67 @@
+// Package logging adds a runtime-adjustable level on top of the standard
+// log package. Messages that are not level-gated still use log directly.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+const (
+	LevelDebug int32 = iota
+	LevelInfo
+	LevelWarning
+)
+
+var level atomic.Int32
+
+func init() {
+	level.Store(LevelInfo)
+}
+
+// ParseLevel maps a log_level option ("debug", "info", "warning"; empty
+// means info) to a level.
+func ParseLevel(name string) (int32, error) {
+	switch name {
+	case "debug":
+		return LevelDebug, nil
+	case "", "info":
+		return LevelInfo, nil
+	case "warning":
+		return LevelWarning, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q (debug, info or warning)", name)
+}
+
+// SetLevel changes the level; it is safe to call while logging.
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Store(l)
+	return nil
+}
+
+// Debugf logs detail such as every line sent to Savant.
+func Debugf(format string, args ...interface{}) {
+	if level.Load() <= LevelDebug {
+		log.Printf(format, args...)
+	}
+}
+
+// Infof logs routine activity such as each command received.
+func Infof(format string, args ...interface{}) {
+	if level.Load() <= LevelInfo {
+		log.Printf(format, args...)
+	}
+}
+
+// Warningf logs problems the operator should act on; it is shown at every
+// level.
+func Warningf(format string, args ...interface{}) {
+	if level.Load() <= LevelWarning {
+		log.Printf(format, args...)
+	}
+}
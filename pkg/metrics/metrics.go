@@ -0,0 +1,207 @@
+// Package metrics implements the few Prometheus instrument types the bridge
+// needs and renders them in the text exposition format, without pulling in
+// the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter only goes up.
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// CounterVec is a set of counters told apart by the value of one label.
+type CounterVec struct {
+	label string
+	mu    sync.Mutex
+	m     map[string]*Counter
+}
+
+func NewCounterVec(label string) *CounterVec {
+	return &CounterVec{label: label, m: make(map[string]*Counter)}
+}
+
+// With returns the counter for a label value, creating it on first use.
+func (v *CounterVec) With(value string) *Counter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.m[value]
+	if !ok {
+		c = &Counter{}
+		v.m[value] = c
+	}
+	return c
+}
+
+// GaugeFunc is a gauge read at scrape time, such as a queue length.
+type GaugeFunc func() float64
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	mu      sync.Mutex
+	bounds  []float64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// NewHistogram returns a histogram with the given ascending upper bounds;
+// the +Inf bucket is implied.
+func NewHistogram(bounds ...float64) *Histogram {
+	return &Histogram{bounds: bounds, buckets: make([]uint64, len(bounds))}
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.bounds {
+		if v <= b {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Registry holds named instruments and writes them out in registration
+// order.
+type Registry struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+type entry struct {
+	name, help, kind string
+	write            func(w io.Writer, name string)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds an instrument: a *Counter, *CounterVec, GaugeFunc or
+// *Histogram.
+func (r *Registry) Register(name, help string, instrument interface{}) {
+	var e entry
+	switch m := instrument.(type) {
+	case *Counter:
+		e = entry{kind: "counter", write: func(w io.Writer, name string) {
+			fmt.Fprintf(w, "%s %d\n", name, m.Value())
+		}}
+	case *CounterVec:
+		e = entry{kind: "counter", write: m.write}
+	case GaugeFunc:
+		e = entry{kind: "gauge", write: func(w io.Writer, name string) {
+			fmt.Fprintf(w, "%s %s\n", name, formatFloat(m()))
+		}}
+	case *Histogram:
+		e = entry{kind: "histogram", write: m.write}
+	default:
+		panic(fmt.Sprintf("metrics: cannot register %T", instrument))
+	}
+	e.name, e.help = name, help
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+}
+
+// WriteText writes every instrument in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	entries := append([]entry(nil), r.entries...)
+	r.mu.Unlock()
+
+	for _, e := range entries {
+		fmt.Fprintf(w, "# HELP %s %s\n", e.name, escapeHelp(e.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", e.name, e.kind)
+		e.write(w, e.name)
+	}
+}
+
+// Handler serves the registry for Prometheus to scrape.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+func (v *CounterVec) write(w io.Writer, name string) {
+	v.mu.Lock()
+	values := make([]string, 0, len(v.m))
+	for value := range v.m {
+		values = append(values, value)
+	}
+	counters := make(map[string]uint64, len(v.m))
+	for value, c := range v.m {
+		counters[value] = c.Value()
+	}
+	v.mu.Unlock()
+
+	sort.Strings(values)
+	for _, value := range values {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, v.label, escapeLabel(value), counters[value])
+	}
+}
+
+func (h *Histogram) write(w io.Writer, name string) {
+	h.mu.Lock()
+	buckets := append([]uint64(nil), h.buckets...)
+	count, sum := h.count, h.sum
+	h.mu.Unlock()
+
+	for i, b := range h.bounds {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(b), buckets[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, formatFloat(sum))
+	fmt.Fprintf(w, "%s_count %d\n", name, count)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
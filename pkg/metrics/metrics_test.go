@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	requests := &Counter{}
+	requests.Inc()
+	requests.Add(2)
+	r.Register("bridge_requests_total", "Requests handled.", requests)
+
+	failures := NewCounterVec("code")
+	failures.With("timeout").Inc()
+	failures.With(`say "hi"`).Add(2)
+	failures.With("a\\b\nc").Inc()
+	r.Register("bridge_failures_total", "Failures by code.", failures)
+
+	r.Register("bridge_queue_length", "Line one\nback\\slash.", GaugeFunc(func() float64 { return 1.5 }))
+	r.Register("bridge_nan", "Not a number.", GaugeFunc(func() float64 { return math.NaN() }))
+
+	latency := NewHistogram(0.1, 1)
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(2)
+	r.Register("bridge_latency_seconds", "Latency.", latency)
+
+	want := `# HELP bridge_requests_total Requests handled.
+# TYPE bridge_requests_total counter
+bridge_requests_total 3
+# HELP bridge_failures_total Failures by code.
+# TYPE bridge_failures_total counter
+bridge_failures_total{code="a\\b\nc"} 1
+bridge_failures_total{code="say \"hi\""} 2
+bridge_failures_total{code="timeout"} 1
+# HELP bridge_queue_length Line one\nback\\slash.
+# TYPE bridge_queue_length gauge
+bridge_queue_length 1.5
+# HELP bridge_nan Not a number.
+# TYPE bridge_nan gauge
+bridge_nan NaN
+# HELP bridge_latency_seconds Latency.
+# TYPE bridge_latency_seconds histogram
+bridge_latency_seconds_bucket{le="0.1"} 1
+bridge_latency_seconds_bucket{le="1"} 2
+bridge_latency_seconds_bucket{le="+Inf"} 3
+bridge_latency_seconds_sum 2.55
+bridge_latency_seconds_count 3
+`
+
+	var got strings.Builder
+	r.WriteText(&got)
+	if got.String() != want {
+		t.Errorf("WriteText output differs\ngot:\n%s\nwant:\n%s", got.String(), want)
+	}
+}
+
+func TestRegisterUnknownPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register accepted an int")
+		}
+	}()
+	NewRegistry().Register("bridge_bad", "Bad.", 1)
+}
@@ -0,0 +1,271 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/savant"
+)
+
+// Finding is a problem found in a profile.
+type Finding struct {
+	Line    int
+	Error   bool
+	Message string
+}
+
+func (f Finding) String() string {
+	level := "warning"
+	if f.Error {
+		level = "error"
+	}
+	return fmt.Sprintf("%d: %s: %s", f.Line, level, f.Message)
+}
+
+// placeholder stands for a value Savant fills in at runtime.
+const placeholder = "\x00"
+
+// sentCommand is the text one <command> element sends, with action
+// arguments and state variables replaced by placeholders.
+type sentCommand struct {
+	line   int
+	action string
+	text   strings.Builder
+	// open is set when a state variable is sent; it may hold any number
+	// of comma-separated values.
+	open bool
+	// raw is set for commands that are not plain text (checksums, macros).
+	raw bool
+}
+
+// statusRule is the constant text of one <status_message>.
+type statusRule struct {
+	line      int
+	name      string
+	constants []string
+}
+
+// Check parses a Savant profile and reports commands the bridge does not
+// know, commands sent with the wrong number of arguments (errors), and
+// state parse rules for attributes the bridge is not known to emit
+// (warnings).
+func Check(r io.Reader) ([]Finding, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	commands, rules, err := parseProfile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, c := range commands {
+		findings = append(findings, checkCommand(c)...)
+	}
+	for _, r := range rules {
+		findings = append(findings, checkRule(r)...)
+	}
+	return findings, nil
+}
+
+func parseProfile(data []byte) ([]*sentCommand, []*statusRule, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	lineAt := func() int {
+		return 1 + bytes.Count(data[:dec.InputOffset()], []byte("\n"))
+	}
+
+	var (
+		commands []*sentCommand
+		rules    []*statusRule
+		action   string
+		cmd      *sentCommand
+		rule     *statusRule
+		// text collects the character data of the current leaf element.
+		text     *strings.Builder
+		inAction bool
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", lineAt(), err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "action":
+				action, inAction = attr(t, "name"), true
+			case "command":
+				if inAction {
+					cmd = &sentCommand{line: lineAt(), action: action}
+				}
+			case "command_string":
+				if cmd != nil {
+					if attr(t, "macro_name") != "" {
+						cmd.raw = true
+					}
+					text = &cmd.text
+				}
+			case "parameter":
+				if cmd != nil {
+					switch {
+					case attr(t, "action_argument") != "":
+						cmd.text.WriteString(placeholder)
+					case attr(t, "state_variable") != "":
+						cmd.text.WriteString(placeholder)
+						cmd.open = true
+					default:
+						text = &cmd.text
+					}
+				}
+			case "checksum_parameter":
+				if cmd != nil {
+					cmd.raw = true
+				}
+			case "status_message":
+				rule = &statusRule{line: lineAt(), name: attr(t, "name")}
+			case "constant":
+				if rule != nil {
+					rule.constants = append(rule.constants, "")
+					text = &strings.Builder{}
+				}
+			}
+		case xml.CharData:
+			if text != nil {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "action":
+				inAction = false
+			case "command":
+				if cmd != nil && !cmd.raw && strings.TrimSpace(cmd.text.String()) != "" {
+					commands = append(commands, cmd)
+				}
+				cmd = nil
+			case "constant":
+				if rule != nil && text != nil {
+					rule.constants[len(rule.constants)-1] = text.String()
+				}
+			case "status_message":
+				if rule != nil {
+					rules = append(rules, rule)
+				}
+				rule = nil
+			}
+			text = nil
+		}
+	}
+	return commands, rules, nil
+}
+
+func checkCommand(c *sentCommand) []Finding {
+	fields := strings.Split(strings.TrimSpace(c.text.String()), ",")
+	name := fields[0]
+	if strings.Contains(name, placeholder) {
+		return nil
+	}
+	where := fmt.Sprintf("action %s: ", c.action)
+
+	spec, ok := savant.LookupCommand(name)
+	if !ok {
+		return []Finding{{Line: c.line, Error: true, Message: where + fmt.Sprintf("unknown command %q", name)}}
+	}
+
+	n := len(fields) - 1
+	if n == 1 && fields[1] == "" {
+		// A trailing comma with nothing after it sends no arguments.
+		n = 0
+	}
+	switch {
+	case n < spec.MinArgs && !c.open:
+		return []Finding{{Line: c.line, Error: true, Message: where + fmt.Sprintf("%s needs at least %d arguments, profile sends %d", name, spec.MinArgs, n)}}
+	case spec.MaxArgs() >= 0 && n > spec.MaxArgs():
+		return []Finding{{Line: c.line, Error: true, Message: where + fmt.Sprintf("%s takes at most %d arguments, profile sends %d", name, spec.MaxArgs(), n)}}
+	}
+	return nil
+}
+
+func checkRule(r *statusRule) []Finding {
+	joined := strings.Join(r.constants, "")
+	i := strings.Index(joined, "attr_name=")
+	if i < 0 {
+		return nil
+	}
+	name := joined[i+len("attr_name="):]
+	end := strings.Index(name, "&")
+	if end < 0 {
+		// The attribute name is parsed from the line, not matched.
+		return nil
+	}
+	name = name[:end]
+	if name == "" || emitted(domainOf(joined), name) {
+		return nil
+	}
+	return []Finding{{Line: r.line, Message: fmt.Sprintf("status message %s: attribute %q is not one the bridge is known to emit", r.name, name)}}
+}
+
+// domainOf returns the domain a rule is limited to by an entity_id=<domain>.
+// constant, or "".
+func domainOf(constants string) string {
+	rest, ok := strings.CutPrefix(constants, "entity_id=")
+	if !ok {
+		return ""
+	}
+	if i := strings.Index(rest, "."); i > 0 && !strings.Contains(rest[:i], "&") {
+		return rest[:i]
+	}
+	return ""
+}
+
+// emitted reports whether the bridge sends attr_name=name for entities of
+// domain, or of any domain when domain is "" or unknown. Beyond the
+// attribute table it sends the merged "attributes" line under the entity
+// ID and array elements under their index.
+func emitted(domain, name string) bool {
+	if strings.Contains(name, ".") {
+		return true
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return true
+	}
+	for _, a := range commonAttributes {
+		if a == name {
+			return true
+		}
+	}
+	if attrs, ok := Domains[domain]; ok {
+		return hasAttribute(attrs, name)
+	}
+	for _, attrs := range Domains {
+		if hasAttribute(attrs, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttribute(attrs []Attribute, name string) bool {
+	for _, a := range attrs {
+		if a.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
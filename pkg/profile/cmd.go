@@ -0,0 +1,126 @@
+package profile
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `usage: bridge profile <command> [flags]
+
+commands:
+  generate   write a Savant profile for the bridge's commands
+  check      report commands and parse rules a profile gets wrong
+`
+
+// Main runs the "bridge profile" subcommands and returns the exit code.
+func Main(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "generate":
+		return runGenerate(args[1:])
+	case "check":
+		return runCheck(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown profile command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet("profile generate", flag.ContinueOnError)
+	domains := fs.String("domains", "", "comma-separated entity domains (default: domains of the entity list, or all)")
+	entitiesFile := fs.String("entities", "", "exported entity list: /api/states JSON or a JSON array of entity IDs")
+	live := fs.Bool("live", false, "read the entity list from Home Assistant")
+	haURL := fs.String("ha-url", "http://supervisor/core", "Home Assistant URL for -live")
+	token := fs.String("token", os.Getenv("SUPERVISOR_TOKEN"), "access token for -live")
+	port := fs.Int("port", 8080, "bridge TCP port")
+	output := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	opts := GenerateOptions{Port: *port}
+	if *domains != "" {
+		for _, d := range strings.Split(*domains, ",") {
+			if d = strings.TrimSpace(d); d != "" {
+				opts.Domains = append(opts.Domains, d)
+			}
+		}
+	}
+
+	var err error
+	switch {
+	case *entitiesFile != "" && *live:
+		fmt.Fprintln(os.Stderr, "profile generate: -entities and -live are mutually exclusive")
+		return 2
+	case *entitiesFile != "":
+		opts.Entities, err = LoadEntities(*entitiesFile)
+	case *live:
+		opts.Entities, err = FetchEntities(*haURL, *token)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "profile generate: reading entities: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "profile generate: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := Generate(w, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "profile generate: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("profile check", flag.ContinueOnError)
+	strict := fs.Bool("strict", false, "fail on warnings too")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: bridge profile check [-strict] <file.xml>...")
+		return 2
+	}
+
+	failed := false
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "profile check: %v\n", err)
+			failed = true
+			continue
+		}
+		findings, err := Check(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, finding := range findings {
+			fmt.Printf("%s:%s\n", path, finding)
+			if finding.Error || *strict {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,76 @@
+package profile
+
+import "sort"
+
+// Attribute is a value the bridge reports for entities of a domain, as
+// attr_name=<Name> on a state line.
+type Attribute struct {
+	Name    string
+	Integer bool
+}
+
+// Domains lists, per entity domain, the attributes that generated profiles
+// parse. "state" is always reported and comes first.
+var Domains = map[string][]Attribute{
+	"alarm_control_panel": attrs("state", "code_format", "changed_by"),
+	"automation":          attrs("state", "last_triggered"),
+	"binary_sensor":       attrs("state", "device_class"),
+	"button":              attrs("state"),
+	"camera":              attrs("state", "entity_picture"),
+	"climate": attrs("state", "current_temperature", "temperature", "target_temp_low", "target_temp_high",
+		"hvac_action", "hvac_modes", "fan_mode", "fan_modes", "preset_mode", "preset_modes",
+		"swing_mode", "swing_modes", "current_humidity", "humidity", "aux_heat", "min_temp", "max_temp"),
+	"cover":          append(attrs("state"), intAttrs("current_position", "current_tilt_position")...),
+	"fan":            append(attrs("state", "preset_mode", "preset_modes", "oscillating", "direction"), intAttrs("percentage")...),
+	"humidifier":     attrs("state", "humidity", "current_humidity", "mode", "available_modes", "action"),
+	"input_boolean":  attrs("state"),
+	"input_datetime": attrs("state"),
+	"input_number":   attrs("state", "min", "max", "step"),
+	"input_select":   attrs("state", "options"),
+	"input_text":     attrs("state"),
+	"lawn_mower":     attrs("state"),
+	"light": append(attrs("state", "color_mode", "hs_color", "rgb_color", "color_temp_kelvin", "effect",
+		"effect_list", "min_color_temp_kelvin", "max_color_temp_kelvin", "supported_color_modes"), intAttrs("brightness")...),
+	"lock": attrs("state"),
+	"media_player": attrs("state", "volume_level", "is_volume_muted", "media_title", "media_artist",
+		"media_album_name", "media_content_type", "media_duration", "media_position", "source", "source_list",
+		"sound_mode", "sound_mode_list", "shuffle", "repeat", "group_members", "entity_picture", "app_name"),
+	"remote":       attrs("state", "current_activity", "activity_list"),
+	"scene":        attrs("state"),
+	"script":       attrs("state"),
+	"sensor":       attrs("state", "unit_of_measurement", "device_class"),
+	"switch":       attrs("state"),
+	"vacuum":       attrs("state", "status", "battery_level", "fan_speed", "fan_speed_list"),
+	"valve":        append(attrs("state"), intAttrs("current_position")...),
+	"water_heater": attrs("state", "current_temperature", "temperature", "operation_mode", "operation_list", "away_mode"),
+	"weather":      attrs("state", "temperature", "humidity", "pressure", "wind_speed", "wind_bearing", "forecast"),
+}
+
+// commonAttributes are reported for entities of every domain.
+var commonAttributes = []string{"friendly_name", "supported_features", "icon", "assumed_state"}
+
+// DomainNames returns the known domains in order.
+func DomainNames() []string {
+	names := make([]string, 0, len(Domains))
+	for name := range Domains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func attrs(names ...string) []Attribute {
+	list := make([]Attribute, len(names))
+	for i, n := range names {
+		list[i] = Attribute{Name: n}
+	}
+	return list
+}
+
+func intAttrs(names ...string) []Attribute {
+	list := attrs(names...)
+	for i := range list {
+		list[i].Integer = true
+	}
+	return list
+}
@@ -0,0 +1,96 @@
+package profile
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entity is an entity to pre-fill a generated profile with.
+type Entity struct {
+	ID   string
+	Name string
+}
+
+// Domain returns the domain part of the entity ID.
+func (e Entity) Domain() string {
+	if i := strings.Index(e.ID, "."); i > 0 {
+		return e.ID[:i]
+	}
+	return ""
+}
+
+// LoadEntities reads an exported entity list. The file is either the
+// output of HA's /api/states (a JSON array of state objects) or a JSON
+// array of entity IDs.
+func LoadEntities(path string) ([]Entity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := parseEntities(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return entities, nil
+}
+
+// FetchEntities reads the live entity list from HA's REST API.
+func FetchEntities(haURL, token string) ([]Entity, error) {
+	req, err := http.NewRequest("GET", strings.TrimRight(haURL, "/")+"/api/states", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /api/states: %s", resp.Status)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return parseEntities(raw)
+}
+
+func parseEntities(data []byte) ([]Entity, error) {
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err == nil {
+		entities := make([]Entity, len(ids))
+		for i, id := range ids {
+			entities[i] = Entity{ID: id}
+		}
+		return sortEntities(entities), nil
+	}
+
+	var states []struct {
+		EntityID   string `json:"entity_id"`
+		Attributes struct {
+			FriendlyName string `json:"friendly_name"`
+		} `json:"attributes"`
+	}
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of states or entity IDs: %v", err)
+	}
+	entities := make([]Entity, 0, len(states))
+	for _, st := range states {
+		if st.EntityID != "" {
+			entities = append(entities, Entity{ID: st.EntityID, Name: st.Attributes.FriendlyName})
+		}
+	}
+	return sortEntities(entities), nil
+}
+
+func sortEntities(entities []Entity) []Entity {
+	sort.Slice(entities, func(i, j int) bool { return entities[i].ID < entities[j].ID })
+	return entities
+}
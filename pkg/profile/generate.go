@@ -0,0 +1,326 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/savant"
+)
+
+// GenerateOptions selects what goes into a generated profile.
+type GenerateOptions struct {
+	// Domains to generate parse rules and actions for. When empty, the
+	// domains of Entities are used, or every known domain.
+	Domains []string
+	// Entities pre-fill the entity list the profile subscribes to.
+	Entities []Entity
+	// Port is the bridge's TCP port.
+	Port int
+}
+
+type genRule struct {
+	Name  string
+	Attr  string
+	State string
+	Type  string
+}
+
+type genDomain struct {
+	Name  string
+	Rules []genRule
+}
+
+type genArg struct {
+	Name string
+	Note string
+}
+
+type genAction struct {
+	Name    string
+	Command string
+	Args    []genArg
+}
+
+type genData struct {
+	Port      int
+	Domains   []genDomain
+	Actions   []genAction
+	Filter    string
+	Entities  []Entity
+	EntityIDs string
+}
+
+// Generate writes a Savant profile with status messages for the state
+// lines of the selected domains and one custom action per command of the
+// command table acting on them. Actions send only the required arguments;
+// optional ones can be added to the profile by hand.
+func Generate(w io.Writer, opts GenerateOptions) error {
+	domains, err := selectDomains(opts)
+	if err != nil {
+		return err
+	}
+	if opts.Port == 0 {
+		opts.Port = 8080
+	}
+
+	data := genData{Port: opts.Port}
+	filter := map[string]bool{}
+	selected := map[string]bool{}
+	for _, d := range domains {
+		selected[d] = true
+		gd := genDomain{Name: d}
+		for _, a := range Domains[d] {
+			typ := "string"
+			if a.Integer {
+				typ = "integer"
+			}
+			gd.Rules = append(gd.Rules, genRule{
+				Name:  camelCase(d) + camelCase(a.Name),
+				Attr:  a.Name,
+				State: camelCase(d) + camelCase(a.Name),
+				Type:  typ,
+			})
+			filter[a.Name] = true
+		}
+		data.Domains = append(data.Domains, gd)
+	}
+	data.Filter = strings.Join(sortedKeys(filter), ",")
+
+	for _, c := range savant.Commands {
+		if c.Domain == "" || !selected[c.Domain] {
+			continue
+		}
+		action := genAction{Name: camelCase(c.Name), Command: c.Name}
+		for i, name := range c.Args[:c.MinArgs] {
+			arg := genArg{Name: camelCase(name), Note: strings.ReplaceAll(name, "_", " ")}
+			if i == 0 && name == "entity_id" {
+				arg = genArg{Name: "Address1", Note: "Entity ID"}
+			}
+			action.Args = append(action.Args, arg)
+		}
+		data.Actions = append(data.Actions, action)
+	}
+
+	var ids []string
+	for _, e := range opts.Entities {
+		if selected[e.Domain()] {
+			data.Entities = append(data.Entities, e)
+			ids = append(ids, e.ID)
+		}
+	}
+	data.EntityIDs = strings.Join(ids, ",")
+
+	var buf bytes.Buffer
+	if err := profileTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func selectDomains(opts GenerateOptions) ([]string, error) {
+	if len(opts.Domains) > 0 {
+		var domains []string
+		for _, d := range opts.Domains {
+			if _, ok := Domains[d]; !ok {
+				return nil, fmt.Errorf("unknown domain %q (known: %s)", d, strings.Join(DomainNames(), ", "))
+			}
+			domains = append(domains, d)
+		}
+		return domains, nil
+	}
+	if len(opts.Entities) > 0 {
+		seen := map[string]bool{}
+		for _, e := range opts.Entities {
+			if _, ok := Domains[e.Domain()]; ok {
+				seen[e.Domain()] = true
+			}
+		}
+		return sortedKeys(seen), nil
+	}
+	return DomainNames(), nil
+}
+
+// camelCase turns snake_case into the CamelCase Savant uses for action and
+// state names.
+func camelCase(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+var profileTemplate = template.Must(template.New("profile").Funcs(template.FuncMap{"x": xmlEscape}).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<component xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="racepoint_component_profile.xsd" manufacturer="Hass" model="Generated" alias="Home Assistant"
+  device_class="Lighting_controller" minimum_component_engine_version="0" rpm_xml_version="3.8">
+
+  <notes>
+    Generated by "bridge profile generate" for: {{range $i, $d := .Domains}}{{if $i}}, {{end}}{{$d.Name}}{{end}}.
+    State names are the domain and attribute in CamelCase followed by the
+    object ID, e.g. LightBrightness_kitchen for light.kitchen.
+{{- range .Entities}}
+    {{x .ID}}{{if .Name}} - {{x .Name}}{{end}}
+{{- end}}
+  </notes>
+  <control_interfaces preferred="ip">
+    <ip port="{{.Port}}" response_time_length_ms="1000" protocol="tcp">
+        <send_postfix type="hex">0A</send_postfix>
+        <receive_end_condition test_condition="data" type="hex">0A</receive_end_condition>
+    </ip>
+  </control_interfaces>
+  <media_interfaces>
+    <data name_on_component="Ethernet">
+      <combined_media>
+        <data_media type="ethernet"/>
+        <control port="{{.Port}}"/>
+      </combined_media>
+    </data>
+    <internal name_on_component="Home Assistant">
+      <environmental_media/>
+    </internal>
+  </media_interfaces>
+  <state_variable_list>
+    <state_variable name="subscribe_all_events" owning_logical_component="Home Assistant" state_center_type="string" user_editable="yes">NO</state_variable>
+    <state_variable name="state_filter" owning_logical_component="Home Assistant" state_center_type="string" state_center_binding="state_filter" user_editable="yes">{{x .Filter}}</state_variable>
+    <state_variable name="HassEntityArray" owning_logical_component="Home Assistant" state_center_type="string" state_center_binding="HassEntityArray" user_editable="yes">{{x .EntityIDs}}</state_variable>
+  </state_variable_list>
+  <logical_component logical_component_name="Home Assistant">
+    <implementation>
+      <internal name_on_component="Home Assistant"/>
+    </implementation>
+
+    <status_messages>
+      <status_message name="websocket_connected">
+        <constant type="character">hass_websocket_connected,</constant>
+        <data type="character" terminator_type="end_of_data">
+          <update state="ws_reconnected_at" type="string"></update>
+        </data>
+        <run_elements>
+          <actions>
+            <action name="resubscribe">
+              <execute_action_after_delay ms_delay="100" action_name="SubscribeEvents" action_type="CUSTOM"></execute_action_after_delay>
+              <execute_action_after_delay ms_delay="200" action_name="StateFilter" action_type="CUSTOM"></execute_action_after_delay>
+              <execute_action_after_delay ms_delay="400" action_name="SubscribeEntities" action_type="CUSTOM"></execute_action_after_delay>
+            </action>
+          </actions>
+        </run_elements>
+      </status_message>
+{{- range $d := .Domains}}
+{{- range .Rules}}
+
+      <status_message name="{{.Name}}">
+        <constant type="character">entity_id={{$d.Name}}.</constant>
+        <data type="character" terminator_type="character" terminator="&amp;">
+          <update state="EntityID" type="string"></update>
+        </data>
+        <constant type="character">substitute_id=</constant>
+        <data type="character" terminator_type="character" terminator="&amp;">
+          <update state="SubstituteID" type="string"></update>
+        </data>
+{{- if eq .Attr "state"}}
+        <constant type="character">parent_keys=&amp;attr_name=state&amp;attr_value=</constant>
+{{- else}}
+        <constant type="character">parent_keys=</constant>
+        <data type="character" terminator_type="character" terminator="&amp;">
+          <update state="ParentKeys" type="string"></update>
+        </data>
+        <constant type="character">attr_name={{.Attr}}&amp;attr_value=</constant>
+{{- end}}
+        <data type="character" terminator_type="end_of_data">
+          <update state="{{.State}}" type="{{.Type}}"></update>
+        </data>
+        <append_data_to_state_names state="EntityID" />
+      </status_message>
+{{- end}}
+{{- end}}
+
+      <status_message name="Unhandled">
+        <data type="character" terminator_type="end_of_data">
+          <update state="Unhandled" type="string"></update>
+        </data>
+      </status_message>
+    </status_messages>
+
+    <custom_component_actions>
+      <action name="SubscribeEvents">
+        <execute_on_state_variable_condition name="subscribe_all_events" test_condition="not_equal">NO</execute_on_state_variable_condition>
+        <command_interface interface="ip">
+          <command response_required="no">
+            <command_string type="character">subscribe_events</command_string>
+          </command>
+        </command_interface>
+      </action>
+      <action name="StateFilter">
+        <command_interface interface="ip">
+          <command response_required="no">
+            <command_string type="character">state_filter,</command_string>
+            <parameter_list>
+              <parameter parameter_data_type="character" state_variable="state_filter"/>
+            </parameter_list>
+          </command>
+        </command_interface>
+      </action>
+      <action name="SubscribeEntities">
+        <command_interface interface="ip">
+          <command response_required="no">
+            <command_string type="character">subscribe_entity,</command_string>
+            <parameter_list>
+              <parameter parameter_data_type="character" state_variable="HassEntityArray"/>
+            </parameter_list>
+            <delay ms_delay="50"/>
+          </command>
+        </command_interface>
+      </action>
+{{- range .Actions}}
+      <action name="{{.Name}}">
+{{- range .Args}}
+        <action_argument name="{{.Name}}" note="{{x .Note}}"/>
+{{- end}}
+        <command_interface interface="ip">
+          <command response_required="no">
+            <command_string type="character">{{.Command}}{{if .Args}},{{end}}</command_string>
+{{- if .Args}}
+            <parameter_list>
+{{- range $i, $a := .Args}}
+{{- if $i}}
+              <parameter parameter_data_type="character">,</parameter>
+{{- end}}
+              <parameter parameter_data_type="character" action_argument="{{$a.Name}}"/>
+{{- end}}
+            </parameter_list>
+{{- end}}
+            <delay ms_delay="10"/>
+          </command>
+        </command_interface>
+      </action>
+{{- end}}
+    </custom_component_actions>
+  </logical_component>
+
+</component>
+`))
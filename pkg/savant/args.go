@@ -0,0 +1,89 @@
+package savant
+
+import (
+	"strconv"
+	"strings"
+)
+
+// parseKeyValues turns key=value arguments into service data. Values are
+// typed: integers, floats, booleans and null are converted, quoted values
+// stay strings.
+func parseKeyValues(args []string) map[string]interface{} {
+	data := make(map[string]interface{})
+	for _, kv := range args {
+		kvParts := strings.SplitN(kv, "=", 2)
+		if len(kvParts) != 2 || strings.TrimSpace(kvParts[0]) == "" {
+			continue
+		}
+		data[strings.TrimSpace(kvParts[0])] = parseTypedValue(kvParts[1])
+	}
+	return data
+}
+
+func parseTypedValue(v string) interface{} {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && (v[0] == '"' && v[len(v)-1] == '"' || v[0] == '\'' && v[len(v)-1] == '\'') {
+		return v[1 : len(v)-1]
+	}
+	switch strings.ToLower(v) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null", "none":
+		return nil
+	}
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+// splitArgs splits a Savant command line on commas. Double quotes protect
+// commas: a field that starts with a quote has the quotes removed, while
+// quotes inside a field (title="a, b") are kept so that typed key=value
+// parsing can see them. A backslash escapes a following quote, comma or
+// backslash. Lines without quotes split exactly like strings.Split.
+func splitArgs(line string) []string {
+	var fields []string
+	var b strings.Builder
+	inQuotes, quotedField := false, false
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes) && strings.ContainsRune("\",\\", runes[i+1]):
+			i++
+			b.WriteRune(runes[i])
+		case r == '"' && !inQuotes && b.Len() == 0 && !quotedField:
+			inQuotes, quotedField = true, true
+		case r == '"' && inQuotes && quotedField:
+			inQuotes = false
+		case r == '"':
+			inQuotes = !inQuotes
+			b.WriteRune(r)
+		case r == ',' && !inQuotes:
+			fields = append(fields, b.String())
+			b.Reset()
+			quotedField = false
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(fields, b.String())
+}
+
+// quoteField prepares a value for a comma-separated line to Savant, using
+// the same quoting rules as splitArgs.
+func quoteField(v string) string {
+	v = strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+	if !strings.ContainsAny(v, ",\"") {
+		return v
+	}
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)
+	return `"` + v + `"`
+}
@@ -0,0 +1,55 @@
+package savant
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", []string{""}},
+		{"light.kitchen", []string{"light.kitchen"}},
+		{"a,b,,c", []string{"a", "b", "", "c"}},
+		{"a,b,", []string{"a", "b", ""}},
+		{`a,"b, c",d`, []string{"a", "b, c", "d"}},
+		{`notify,title="a, b",x`, []string{"notify", `title="a, b"`, "x"}},
+		{`a\,b,c`, []string{"a,b", "c"}},
+		{`a\"b,c\\d`, []string{`a"b`, `c\d`}},
+		{`"say \"hi\", now"`, []string{`say "hi", now`}},
+		{`a\b`, []string{`a\b`}},
+		{`"unterminated, x`, []string{"unterminated, x"}},
+	}
+	for _, tt := range tests {
+		if got := splitArgs(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteFieldRoundTrip(t *testing.T) {
+	for _, v := range []string{"plain", "a, b", `say "hi"`, `back\slash, "both"`} {
+		got := splitArgs(quoteField(v) + ",next")
+		if want := []string{v, "next"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("splitArgs(quoteField(%q)) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	got := parseKeyValues([]string{"brightness=128", "rgb=0.5", "on=true", "off=False", "x=null", `name="42"`, "word=hello", "=skipped", "noequals"})
+	want := map[string]interface{}{
+		"brightness": int64(128),
+		"rgb":        0.5,
+		"on":         true,
+		"off":        false,
+		"x":          nil,
+		"name":       "42",
+		"word":       "hello",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeyValues = %#v, want %#v", got, want)
+	}
+}
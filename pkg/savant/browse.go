@@ -0,0 +1,71 @@
+package savant
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+const browsePageSize = 20
+
+// browseMedia asks HA for the children of a media node and sends one page
+// of them to Savant:
+//
+//	browse_media,<entity>,<title>,<page>,<pages>,<total>
+//	browse_media_item,<entity>,<index>,<title>,<content_type>,<content_id>,<can_play>,<can_expand>,<thumbnail>
+//	browse_media_end,<entity>
+//
+// Errors are reported as browse_media_error,<entity>,<message>. Replies go
+// to the asking client only.
+func (s *Server) browseMedia(conn net.Conn, entityID, contentType, contentID string, page int) {
+	if entities, ok := targetEntities(entityID); !ok || len(entities) != 1 {
+		s.reply(conn, fmt.Sprintf("browse_media_error,%s,a single media player is required\n", s.haClient.OutboundID(entityID)))
+		return
+	}
+	req := map[string]interface{}{
+		"type":      "media_player/browse_media",
+		"entity_id": entityID,
+	}
+	if contentType != "" && contentID != "" {
+		req["media_content_type"] = contentType
+		req["media_content_id"] = contentID
+	}
+
+	// Replies name the entity the way Savant addressed it
+	id := s.haClient.OutboundID(entityID)
+	s.haClient.SendRequest(req, func(result interface{}, err error) {
+		if err != nil {
+			log.Printf("browse_media for %s failed: %v", entityID, err)
+			s.reply(conn, fmt.Sprintf("browse_media_error,%s,%s\n", id, quoteField(err.Error())))
+			return
+		}
+		node, _ := result.(map[string]interface{})
+		children, _ := node["children"].([]interface{})
+
+		page, pages, start, end := paginate(len(children), browsePageSize, page)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "browse_media,%s,%s,%d,%d,%d\n", id, quoteField(mediaString(node, "title")), page, pages, len(children))
+		for i := start; i < end; i++ {
+			child, _ := children[i].(map[string]interface{})
+			fmt.Fprintf(&b, "browse_media_item,%s,%d,%s,%s,%s,%v,%v,%s\n",
+				id, i,
+				quoteField(mediaString(child, "title")),
+				quoteField(mediaString(child, "media_content_type")),
+				quoteField(mediaString(child, "media_content_id")),
+				child["can_play"] == true,
+				child["can_expand"] == true,
+				quoteField(mediaString(child, "thumbnail")))
+		}
+		fmt.Fprintf(&b, "browse_media_end,%s\n", id)
+		s.reply(conn, b.String())
+	})
+}
+
+func mediaString(node map[string]interface{}, key string) string {
+	if v, ok := node[key]; ok && v != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return ""
+}
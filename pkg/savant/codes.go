@@ -0,0 +1,102 @@
+package savant
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+)
+
+// codeArgs maps commands that carry a keypad code to the index of the code
+// argument, so that it never reaches the logs.
+var codeArgs = map[string]int{
+	"alarm_arm_away":          1,
+	"alarm_arm_home":          1,
+	"alarm_arm_night":         1,
+	"alarm_arm_vacation":      1,
+	"alarm_arm_custom_bypass": 1,
+	"alarm_disarm":            1,
+	"alarm_trigger":           1,
+	"lock_lock":               1,
+	"unlock_lock":             1,
+	"lock_open":               1,
+}
+
+// secretKeys are key=value argument keys whose values never reach the
+// logs, whatever the command (e.g. call_service,...,code=1234).
+var secretKeys = map[string]bool{
+	"code":     true,
+	"pin":      true,
+	"password": true,
+}
+
+// redactArgs returns a copy of args that is safe to log.
+func redactArgs(cmd string, args []string) []string {
+	redacted := append([]string(nil), args...)
+	if i, ok := codeArgs[cmd]; ok && i < len(args) && args[i] != "" {
+		redacted[i] = "****"
+	}
+	for i, arg := range redacted {
+		if key, value, ok := strings.Cut(arg, "="); ok && value != "" && secretKeys[strings.ToLower(strings.TrimSpace(key))] {
+			redacted[i] = key + "=****"
+		}
+	}
+	return redacted
+}
+
+// alarmCode picks the code for an alarm command: the one Savant sent, or
+// the configured default. With code checking enabled it is validated
+// against the panel's code_format attribute.
+func (s *Server) alarmCode(cmd, entityID string, args []string) (string, error) {
+	code := ""
+	if len(args) > 1 {
+		code = strings.TrimSpace(args[1])
+	}
+	if code == "" {
+		code = s.current().alarmDefaultCode
+	}
+	if !s.current().checkCodeFormat {
+		return code, nil
+	}
+	st, ok := s.haClient.Entity(entityID)
+	if !ok {
+		// Nothing cached yet, let HA decide.
+		return code, nil
+	}
+	arming := strings.HasPrefix(cmd, "alarm_arm_")
+	return code, checkCodeFormat(st, code, arming)
+}
+
+// lockCode picks the code for a lock command: the one Savant sent, or the
+// code configured for that lock.
+func (s *Server) lockCode(entityID string, args []string) string {
+	if len(args) > 1 {
+		if code := strings.TrimSpace(args[1]); code != "" {
+			return code
+		}
+	}
+	return s.current().lockCodes[entityID]
+}
+
+func checkCodeFormat(st ha.EntityState, code string, arming bool) error {
+	format, _ := st.Attributes["code_format"].(string)
+	if format == "" {
+		return nil
+	}
+	if arming {
+		if required, ok := st.Attributes["code_arm_required"].(bool); ok && !required {
+			return nil
+		}
+	}
+	if code == "" {
+		return fmt.Errorf("code required")
+	}
+	if format == "number" {
+		for _, r := range code {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("code must be numeric")
+			}
+		}
+	}
+	return nil
+}
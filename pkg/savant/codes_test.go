@@ -0,0 +1,107 @@
+package savant
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+)
+
+func TestRedactArgs(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		args []string
+		want []string
+	}{
+		{"alarm_disarm", []string{"partition1", "1234"}, []string{"partition1", "****"}},
+		{"alarm_arm_night", []string{"partition1", ""}, []string{"partition1", ""}},
+		{"alarm_trigger", []string{"partition1"}, []string{"partition1"}},
+		{"unlock_lock", []string{"lock.front", "0000"}, []string{"lock.front", "****"}},
+		{"call_service", []string{"lock", "unlock", "lock.front", "code=1234", "PIN = 42", "password=x", "brightness=5"},
+			[]string{"lock", "unlock", "lock.front", "code=****", "PIN =****", "password=****", "brightness=5"}},
+		{"call_service", []string{"alarm_control_panel", "alarm_disarm", "code="}, []string{"alarm_control_panel", "alarm_disarm", "code="}},
+		{"switch_on", []string{"light.kitchen"}, []string{"light.kitchen"}},
+	}
+	for _, tt := range tests {
+		args := append([]string(nil), tt.args...)
+		if got := redactArgs(tt.cmd, args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("redactArgs(%q, %q) = %q, want %q", tt.cmd, tt.args, got, tt.want)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("redactArgs(%q) modified its input: %q", tt.cmd, args)
+		}
+	}
+}
+
+func TestCheckCodeFormat(t *testing.T) {
+	state := func(attrs map[string]interface{}) ha.EntityState {
+		return ha.EntityState{State: "disarmed", Attributes: attrs}
+	}
+	number := map[string]interface{}{"code_format": "number"}
+	text := map[string]interface{}{"code_format": "text"}
+	noArmCode := map[string]interface{}{"code_format": "number", "code_arm_required": false}
+
+	tests := []struct {
+		name    string
+		attrs   map[string]interface{}
+		code    string
+		arming  bool
+		wantErr bool
+	}{
+		{"no code format", map[string]interface{}{}, "", false, false},
+		{"numeric code", number, "1234", false, false},
+		{"letters for a numeric panel", number, "12a4", false, true},
+		{"missing code", number, "", false, true},
+		{"text code", text, "abc", false, false},
+		{"arming without code when not required", noArmCode, "", true, false},
+		{"disarming still needs a code", noArmCode, "", false, true},
+	}
+	for _, tt := range tests {
+		err := checkCodeFormat(state(tt.attrs), tt.code, tt.arming)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: checkCodeFormat = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAlarmAndLockCodes(t *testing.T) {
+	cfg := &config.Config{
+		Options:   config.Options{AlarmCode: "9999", AlarmCheckCodeFormat: true},
+		LockCodes: map[string]string{"lock.front": "1111"},
+	}
+	s := NewServer(0, cfg, ha.NewClient("", "", func(string) {}))
+
+	alarm := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"alarm_control_panel.home", "1234"}, "1234"},
+		{[]string{"alarm_control_panel.home", " 1234 "}, "1234"},
+		{[]string{"alarm_control_panel.home", ""}, "9999"},
+		{[]string{"alarm_control_panel.home"}, "9999"},
+	}
+	for _, tt := range alarm {
+		// Nothing is cached for the panel, so the format is left to HA.
+		code, err := s.alarmCode("alarm_disarm", tt.args[0], tt.args)
+		if err != nil || code != tt.want {
+			t.Errorf("alarmCode(%q) = %q, %v, want %q", tt.args, code, err, tt.want)
+		}
+	}
+
+	lock := []struct {
+		entity string
+		args   []string
+		want   string
+	}{
+		{"lock.front", []string{"lock.front", "2222"}, "2222"},
+		{"lock.front", []string{"lock.front", " "}, "1111"},
+		{"lock.front", []string{"lock.front"}, "1111"},
+		{"lock.back", []string{"lock.back"}, ""},
+	}
+	for _, tt := range lock {
+		if got := s.lockCode(tt.entity, tt.args); got != tt.want {
+			t.Errorf("lockCode(%q, %q) = %q, want %q", tt.entity, tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package savant
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Savant sends hue and saturation as 0-255 bytes, while HA expects
+// hs_color as [hue 0-360, saturation 0-100].
+func savantHSToHA(hueStr, satStr string) ([]float64, error) {
+	hue, err := parseByte(hueStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hue %q: %v", hueStr, err)
+	}
+	sat, err := parseByte(satStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid saturation %q: %v", satStr, err)
+	}
+	return []float64{
+		round1(float64(hue) * 360.0 / 255.0),
+		round1(float64(sat) * 100.0 / 255.0),
+	}, nil
+}
+
+// parseColorArgs accepts either a single hex string (RRGGBB, #RRGGBB,
+// 0xRRGGBB) or one decimal byte per channel.
+// It returns the channel values and the number of args consumed.
+func parseColorArgs(args []string, channels int) ([]int, int, error) {
+	if len(args) == 0 {
+		return nil, 0, fmt.Errorf("missing color value")
+	}
+	if len(args) >= channels {
+		values, err := parseByteList(args[:channels])
+		if err == nil {
+			return values, channels, nil
+		}
+	}
+	values, err := parseHexColor(args[0], channels)
+	if err != nil {
+		return nil, 0, err
+	}
+	return values, 1, nil
+}
+
+func parseHexColor(s string, channels int) ([]int, error) {
+	hex := strings.TrimSpace(s)
+	hex = strings.TrimPrefix(hex, "#")
+	hex = strings.TrimPrefix(strings.TrimPrefix(hex, "0x"), "0X")
+	if len(hex) != channels*2 {
+		return nil, fmt.Errorf("invalid hex color %q: expected %d digits", s, channels*2)
+	}
+	values := make([]int, channels)
+	for i := range values {
+		v, err := strconv.ParseUint(hex[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex color %q", s)
+		}
+		values[i] = int(v)
+	}
+	return values, nil
+}
+
+func parseByteList(args []string) ([]int, error) {
+	values := make([]int, len(args))
+	for i, a := range args {
+		v, err := parseByte(a)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func parseByte(s string) (int, error) {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > 255 {
+		return 0, fmt.Errorf("%d out of range 0-255", v)
+	}
+	return v, nil
+}
+
+// miredToKelvin converts a Savant mired value (1,000,000 / K) to Kelvin.
+func miredToKelvin(s string) (int, error) {
+	mired, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || mired <= 0 {
+		return 0, fmt.Errorf("invalid mired value %q", s)
+	}
+	return int(math.Round(1000000.0 / mired)), nil
+}
+
+// addTransition sets the optional trailing transition (seconds) argument.
+func addTransition(data map[string]interface{}, args []string) {
+	if len(args) == 0 || args[0] == "" {
+		return
+	}
+	if t, err := strconv.ParseFloat(args[0], 64); err == nil && t >= 0 {
+		data["transition"] = t
+	}
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,80 @@
+package savant
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseColorArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		channels int
+		want     []int
+		used     int
+		wantErr  bool
+	}{
+		{[]string{"255", "128", "0"}, 3, []int{255, 128, 0}, 3, false},
+		{[]string{" 1", "2 ", "3", "4"}, 3, []int{1, 2, 3}, 3, false},
+		{[]string{"ff8000"}, 3, []int{255, 128, 0}, 1, false},
+		{[]string{"#FF8000", "2"}, 3, []int{255, 128, 0}, 1, false},
+		{[]string{"0x0a0b0c"}, 3, []int{10, 11, 12}, 1, false},
+		{[]string{"ff800010"}, 4, []int{255, 128, 0, 16}, 1, false},
+		{[]string{"1", "2", "3", "4", "5"}, 5, []int{1, 2, 3, 4, 5}, 5, false},
+		{nil, 3, nil, 0, true},
+		{[]string{"ff80"}, 3, nil, 0, true},
+		{[]string{"gg8000"}, 3, nil, 0, true},
+		{[]string{"256", "0", "0"}, 3, nil, 0, true},
+		{[]string{"-1", "0", "0"}, 3, nil, 0, true},
+	}
+	for _, tt := range tests {
+		got, used, err := parseColorArgs(tt.args, tt.channels)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseColorArgs(%q, %d) error = %v, want error %v", tt.args, tt.channels, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || used != tt.used {
+			t.Errorf("parseColorArgs(%q, %d) = %v, %d, want %v, %d", tt.args, tt.channels, got, used, tt.want, tt.used)
+		}
+	}
+}
+
+func TestSavantHSToHA(t *testing.T) {
+	tests := []struct {
+		hue, sat string
+		want     []float64
+		wantErr  bool
+	}{
+		{"0", "0", []float64{0, 0}, false},
+		{"255", "255", []float64{360, 100}, false},
+		{"128", "64", []float64{180.7, 25.1}, false},
+		{"300", "0", nil, true},
+		{"0", "x", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := savantHSToHA(tt.hue, tt.sat)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("savantHSToHA(%q, %q) = %v, %v, want %v (error %v)", tt.hue, tt.sat, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMiredToKelvin(t *testing.T) {
+	tests := []struct {
+		mired   string
+		want    int
+		wantErr bool
+	}{
+		{"153", 6536, false},
+		{" 500 ", 2000, false},
+		{"370.4", 2700, false},
+		{"0", 0, true},
+		{"-5", 0, true},
+		{"warm", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := miredToKelvin(tt.mired)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("miredToKelvin(%q) = %d, %v, want %d (error %v)", tt.mired, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
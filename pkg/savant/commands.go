@@ -0,0 +1,191 @@
+package savant
+
+// Command describes one command of the Savant protocol. The table below is
+// the single list of what handleCommand understands; profile generation
+// and checking read it, and handleCommand refuses anything not in it.
+type Command struct {
+	Name string
+	// Domain is the entity domain the command acts on, or "" for
+	// bridge-level and generic commands.
+	Domain string
+	// Args names the arguments in order. The first MinArgs are required.
+	Args    []string
+	MinArgs int
+	// Variadic commands accept further arguments beyond Args, such as
+	// key=value pairs or more entity IDs.
+	Variadic bool
+}
+
+// MaxArgs returns the most arguments the command uses, or -1 if it takes
+// any number.
+func (c Command) MaxArgs() int {
+	if c.Variadic {
+		return -1
+	}
+	return len(c.Args)
+}
+
+var entityArg = []string{"entity_id"}
+
+var Commands = []Command{
+	// Bridge
+	{Name: "subscribe_events"},
+	{Name: "substitute_ids", Args: []string{"savant_id", "entity_id"}, Variadic: true},
+	{Name: "state_filter", Args: []string{"attribute"}, Variadic: true},
+	{Name: "subscribe_entity", Args: entityArg, Variadic: true},
+	{Name: "list_areas", Args: []string{"page"}},
+	{Name: "list_devices", Args: []string{"page"}},
+	{Name: "list_entities", Args: []string{"domain", "page"}},
+
+	// Generic
+	{Name: "call_service", Args: []string{"domain", "service", "entity_id"}, MinArgs: 3, Variadic: true},
+	{Name: "scene_activate", Domain: "scene", Args: []string{"entity_id", "transition"}, MinArgs: 1},
+	{Name: "script_run", Domain: "script", Args: entityArg, MinArgs: 1, Variadic: true},
+	{Name: "automation_trigger", Domain: "automation", Args: []string{"entity_id", "skip_condition"}, MinArgs: 1},
+	{Name: "automation_toggle", Domain: "automation", Args: []string{"entity_id", "state"}, MinArgs: 1},
+	{Name: "notify", Args: []string{"service", "title", "message"}, MinArgs: 3, Variadic: true},
+	{Name: "tts_speak", Domain: "tts", Args: []string{"entity_id", "media_player_entity_id", "message", "language"}, MinArgs: 3, Variadic: true},
+	{Name: "button_press", Domain: "button", Args: entityArg, MinArgs: 1},
+	{Name: "camera_snapshot", Domain: "camera", Args: entityArg, MinArgs: 1},
+
+	// Helpers
+	{Name: "input_select_option", Domain: "input_select", Args: []string{"entity_id", "option"}, MinArgs: 2, Variadic: true},
+	{Name: "input_select_next", Domain: "input_select", Args: []string{"entity_id", "cycle"}, MinArgs: 1},
+	{Name: "input_select_previous", Domain: "input_select", Args: []string{"entity_id", "cycle"}, MinArgs: 1},
+	{Name: "input_number_set", Domain: "input_number", Args: []string{"entity_id", "value"}, MinArgs: 2},
+	{Name: "input_number_increment", Domain: "input_number", Args: entityArg, MinArgs: 1},
+	{Name: "input_number_decrement", Domain: "input_number", Args: entityArg, MinArgs: 1},
+	{Name: "input_text_set", Domain: "input_text", Args: []string{"entity_id", "value"}, MinArgs: 2, Variadic: true},
+	{Name: "input_boolean_on", Domain: "input_boolean", Args: entityArg, MinArgs: 1},
+	{Name: "input_boolean_off", Domain: "input_boolean", Args: entityArg, MinArgs: 1},
+	{Name: "input_boolean_toggle", Domain: "input_boolean", Args: entityArg, MinArgs: 1},
+	{Name: "input_datetime_set", Domain: "input_datetime", Args: []string{"entity_id", "datetime"}, MinArgs: 2},
+
+	// Switches and lights
+	{Name: "switch_on", Domain: "switch", Args: entityArg, MinArgs: 1},
+	{Name: "switch_off", Domain: "switch", Args: entityArg, MinArgs: 1},
+	{Name: "toggle", Domain: "switch", Args: entityArg, MinArgs: 1},
+	{Name: "homeassistant_toggle", Args: entityArg, MinArgs: 1},
+	{Name: "socket_on", Domain: "switch", Args: entityArg, MinArgs: 1},
+	{Name: "socket_off", Domain: "switch", Args: entityArg, MinArgs: 1},
+	{Name: "dimmer_set", Domain: "light", Args: []string{"entity_id", "level", "transition"}, MinArgs: 2},
+	{Name: "light_hs_color", Domain: "light", Args: []string{"entity_id", "hue", "saturation", "transition"}, MinArgs: 3},
+	{Name: "light_rgb_color", Domain: "light", Args: []string{"entity_id", "color", "green", "blue", "transition"}, MinArgs: 2},
+	{Name: "light_rgbw_color", Domain: "light", Args: []string{"entity_id", "color", "green", "blue", "white", "transition"}, MinArgs: 2},
+	{Name: "light_color_temp_kelvin", Domain: "light", Args: []string{"entity_id", "kelvin", "transition"}, MinArgs: 2},
+	{Name: "light_color_temp_mired", Domain: "light", Args: []string{"entity_id", "mired", "transition"}, MinArgs: 2},
+	{Name: "light_effect", Domain: "light", Args: []string{"entity_id", "effect"}, MinArgs: 2},
+	{Name: "light_flash", Domain: "light", Args: []string{"entity_id", "flash"}, MinArgs: 1},
+
+	// Fans and remotes
+	{Name: "fan_on", Domain: "fan", Args: []string{"entity_id", "speed"}, MinArgs: 1},
+	{Name: "fan_off", Domain: "fan", Args: entityArg, MinArgs: 1},
+	{Name: "fan_set", Domain: "fan", Args: []string{"entity_id", "speed"}, MinArgs: 2},
+	{Name: "remote_on", Domain: "remote", Args: entityArg, MinArgs: 1},
+	{Name: "remote_off", Domain: "remote", Args: entityArg, MinArgs: 1},
+	{Name: "remote_send_command", Domain: "remote", Args: []string{"entity_id", "command"}, MinArgs: 2},
+
+	// Covers
+	{Name: "shade_set", Domain: "cover", Args: []string{"entity_id", "level"}, MinArgs: 2},
+	{Name: "shade_open", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "shade_close", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "shade_stop", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "shade_tilt_set", Domain: "cover", Args: []string{"entity_id", "level"}, MinArgs: 2},
+	{Name: "shade_tilt_open", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "shade_tilt_close", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "shade_tilt_stop", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "open_garage_door", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "close_garage_door", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "toggle_garage_door", Domain: "cover", Args: entityArg, MinArgs: 1},
+	{Name: "stop_garage_door", Domain: "cover", Args: entityArg, MinArgs: 1},
+
+	// Security
+	{Name: "alarm_arm_away", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_arm_home", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_arm_night", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_arm_vacation", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_arm_custom_bypass", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_disarm", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "alarm_trigger", Domain: "alarm_control_panel", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "lock_lock", Domain: "lock", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "unlock_lock", Domain: "lock", Args: []string{"entity_id", "code"}, MinArgs: 1},
+	{Name: "lock_open", Domain: "lock", Args: []string{"entity_id", "code"}, MinArgs: 1},
+
+	// Climate
+	{Name: "climate_set_hvac_mode", Domain: "climate", Args: []string{"entity_id", "hvac_mode"}, MinArgs: 2},
+	{Name: "climate_set_single", Domain: "climate", Args: []string{"entity_id", "temperature"}, MinArgs: 2},
+	{Name: "climate_set_temperature_range", Domain: "climate", Args: []string{"entity_id", "target_temp_low", "target_temp_high"}, MinArgs: 3},
+	{Name: "climate_set_fan_mode", Domain: "climate", Args: []string{"entity_id", "fan_mode"}, MinArgs: 2},
+	{Name: "climate_set_preset_mode", Domain: "climate", Args: []string{"entity_id", "preset_mode"}, MinArgs: 2},
+	{Name: "climate_set_swing_mode", Domain: "climate", Args: []string{"entity_id", "swing_mode"}, MinArgs: 2},
+	{Name: "climate_set_humidity", Domain: "climate", Args: []string{"entity_id", "humidity"}, MinArgs: 2},
+	{Name: "climate_turn_on", Domain: "climate", Args: entityArg, MinArgs: 1},
+	{Name: "climate_turn_off", Domain: "climate", Args: entityArg, MinArgs: 1},
+	{Name: "climate_set_aux_heat", Domain: "climate", Args: []string{"entity_id", "aux_heat"}, MinArgs: 2},
+	{Name: "water_heater_set_temperature", Domain: "water_heater", Args: []string{"entity_id", "temperature", "operation_mode"}, MinArgs: 2},
+	{Name: "water_heater_set_operation_mode", Domain: "water_heater", Args: []string{"entity_id", "operation_mode"}, MinArgs: 2},
+	{Name: "water_heater_set_away_mode", Domain: "water_heater", Args: []string{"entity_id", "away_mode"}, MinArgs: 2},
+	{Name: "water_heater_turn_on", Domain: "water_heater", Args: entityArg, MinArgs: 1},
+	{Name: "water_heater_turn_off", Domain: "water_heater", Args: entityArg, MinArgs: 1},
+	{Name: "humidifier_on", Domain: "humidifier", Args: entityArg, MinArgs: 1},
+	{Name: "humidifier_off", Domain: "humidifier", Args: entityArg, MinArgs: 1},
+	{Name: "humidifier_toggle", Domain: "humidifier", Args: entityArg, MinArgs: 1},
+	{Name: "humidifier_set_humidity", Domain: "humidifier", Args: []string{"entity_id", "humidity"}, MinArgs: 2},
+	{Name: "humidifier_set_mode", Domain: "humidifier", Args: []string{"entity_id", "mode"}, MinArgs: 2},
+
+	// Appliances
+	{Name: "vacuum_start", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_pause", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_stop", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_return_to_base", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_locate", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_clean_spot", Domain: "vacuum", Args: entityArg, MinArgs: 1},
+	{Name: "vacuum_set_fan_speed", Domain: "vacuum", Args: []string{"entity_id", "fan_speed"}, MinArgs: 2},
+	{Name: "vacuum_send_command", Domain: "vacuum", Args: []string{"entity_id", "command"}, MinArgs: 2, Variadic: true},
+	{Name: "lawn_mower_start", Domain: "lawn_mower", Args: entityArg, MinArgs: 1},
+	{Name: "lawn_mower_pause", Domain: "lawn_mower", Args: entityArg, MinArgs: 1},
+	{Name: "lawn_mower_dock", Domain: "lawn_mower", Args: entityArg, MinArgs: 1},
+	{Name: "valve_open", Domain: "valve", Args: entityArg, MinArgs: 1},
+	{Name: "valve_close", Domain: "valve", Args: entityArg, MinArgs: 1},
+	{Name: "valve_stop", Domain: "valve", Args: entityArg, MinArgs: 1},
+	{Name: "valve_toggle", Domain: "valve", Args: entityArg, MinArgs: 1},
+	{Name: "valve_set", Domain: "valve", Args: []string{"entity_id", "position"}, MinArgs: 2},
+
+	// Media players
+	{Name: "media_player_play", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_play_pause", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_pause", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_stop", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_next_track", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_previous_track", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_volume_up", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_volume_down", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_set_volume", Domain: "media_player", Args: []string{"entity_id", "volume"}, MinArgs: 2},
+	{Name: "media_player_select_source", Domain: "media_player", Args: []string{"entity_id", "source"}, MinArgs: 2},
+	{Name: "media_player_clear_playlist", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_shuffle_set", Domain: "media_player", Args: []string{"entity_id", "shuffle"}, MinArgs: 2},
+	{Name: "media_player_repeat_set", Domain: "media_player", Args: []string{"entity_id", "repeat"}, MinArgs: 2},
+	{Name: "media_player_media_seek", Domain: "media_player", Args: []string{"entity_id", "seek_position"}, MinArgs: 2},
+	{Name: "media_player_play_media", Domain: "media_player", Args: []string{"entity_id", "content_type", "content_id", "enqueue"}, MinArgs: 2, Variadic: true},
+	{Name: "media_player_on", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_off", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_volume_mute", Domain: "media_player", Args: []string{"entity_id", "muted"}, MinArgs: 2},
+	{Name: "media_player_select_sound_mode", Domain: "media_player", Args: []string{"entity_id", "sound_mode"}, MinArgs: 2},
+	{Name: "media_player_join", Domain: "media_player", Args: []string{"entity_id", "member"}, MinArgs: 2, Variadic: true},
+	{Name: "media_player_unjoin", Domain: "media_player", Args: entityArg, MinArgs: 1},
+	{Name: "media_player_browse_media", Domain: "media_player", Args: []string{"entity_id", "content_type", "content_id", "page"}, MinArgs: 1},
+}
+
+var commandIndex = func() map[string]Command {
+	index := make(map[string]Command, len(Commands))
+	for _, c := range Commands {
+		index[c.Name] = c
+	}
+	return index
+}()
+
+// LookupCommand finds a command in the table.
+func LookupCommand(name string) (Command, bool) {
+	c, ok := commandIndex[name]
+	return c, ok
+}
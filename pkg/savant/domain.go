@@ -0,0 +1,46 @@
+package savant
+
+import "strings"
+
+// onOffDomains lists the domains that implement turn_on, turn_off and
+// toggle themselves. Anything else goes through the homeassistant domain.
+var onOffDomains = map[string]bool{
+	"light":         true,
+	"switch":        true,
+	"input_boolean": true,
+	"fan":           true,
+	"siren":         true,
+	"humidifier":    true,
+	"remote":        true,
+	"media_player":  true,
+	"automation":    true,
+}
+
+// entityDomain returns the domain prefix of an entity ID ("light" for
+// "light.kitchen"), or "" if the ID has no domain.
+func entityDomain(entityID string) string {
+	if i := strings.Index(entityID, "."); i > 0 {
+		return entityID[:i]
+	}
+	return ""
+}
+
+// onOffDomain picks the service domain for a generic on/off/toggle command.
+// A target spanning several domains, or an area/device/label/floor, goes
+// through the homeassistant domain.
+func onOffDomain(target string) string {
+	entities, ok := targetEntities(target)
+	if !ok || len(entities) == 0 {
+		return "homeassistant"
+	}
+	domain := entityDomain(entities[0])
+	for _, e := range entities[1:] {
+		if entityDomain(e) != domain {
+			return "homeassistant"
+		}
+	}
+	if onOffDomains[domain] {
+		return domain
+	}
+	return "homeassistant"
+}
@@ -0,0 +1,122 @@
+package savant
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+)
+
+// The validators below check a value against the helper's attributes from
+// the cached entity state. An entity that is not cached yet is passed
+// through and left for HA to validate.
+
+func (s *Server) validateSelectOption(entityID, option string) error {
+	st, ok := s.haClient.Entity(entityID)
+	if !ok {
+		return nil
+	}
+	options, _ := st.Attributes["options"].([]interface{})
+	for _, o := range options {
+		if fmt.Sprintf("%v", o) == option {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of the options of %s", option, entityID)
+}
+
+func (s *Server) validateNumber(entityID string, value float64) error {
+	st, ok := s.haClient.Entity(entityID)
+	if !ok {
+		return nil
+	}
+	min, hasMin := ha.Number(st.Attributes["min"])
+	max, hasMax := ha.Number(st.Attributes["max"])
+	if hasMin && value < min {
+		return fmt.Errorf("%v is below the minimum %v of %s", value, min, entityID)
+	}
+	if hasMax && value > max {
+		return fmt.Errorf("%v is above the maximum %v of %s", value, max, entityID)
+	}
+	if step, ok := ha.Number(st.Attributes["step"]); ok && step > 0 && hasMin {
+		n := (value - min) / step
+		if math.Abs(n-math.Round(n)) > 1e-6 {
+			return fmt.Errorf("%v is not a multiple of step %v of %s", value, step, entityID)
+		}
+	}
+	return nil
+}
+
+func (s *Server) validateText(entityID, value string) error {
+	st, ok := s.haClient.Entity(entityID)
+	if !ok {
+		return nil
+	}
+	length := len([]rune(value))
+	if min, ok := ha.Number(st.Attributes["min"]); ok && length < int(min) {
+		return fmt.Errorf("text is shorter than %v characters for %s", min, entityID)
+	}
+	if max, ok := ha.Number(st.Attributes["max"]); ok && length > int(max) {
+		return fmt.Errorf("text is longer than %v characters for %s", max, entityID)
+	}
+	if pattern, ok := st.Attributes["pattern"].(string); ok && pattern != "" {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err == nil && !re.MatchString(value) {
+			return fmt.Errorf("text does not match pattern %q of %s", pattern, entityID)
+		}
+	}
+	return nil
+}
+
+// datetimeData builds input_datetime.set_datetime data from a Savant value:
+// "YYYY-MM-DD", "HH:MM[:SS]", "YYYY-MM-DD HH:MM[:SS]" or a Unix timestamp.
+func (s *Server) datetimeData(entityID, value string) (map[string]interface{}, error) {
+	value = strings.TrimSpace(value)
+	hasDate, hasTime := true, true
+	if st, ok := s.haClient.Entity(entityID); ok {
+		if v, ok := st.Attributes["has_date"].(bool); ok {
+			hasDate = v
+		}
+		if v, ok := st.Attributes["has_time"].(bool); ok {
+			hasTime = v
+		}
+	}
+
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return map[string]interface{}{"timestamp": ts}, nil
+	}
+	if t, err := parseTimeLayouts(value, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04"); err == nil {
+		if !hasDate || !hasTime {
+			return nil, fmt.Errorf("%s does not take both a date and a time", entityID)
+		}
+		return map[string]interface{}{"datetime": t.Format("2006-01-02 15:04:05")}, nil
+	}
+	if t, err := parseTimeLayouts(value, "2006-01-02"); err == nil {
+		if !hasDate {
+			return nil, fmt.Errorf("%s does not take a date", entityID)
+		}
+		return map[string]interface{}{"date": t.Format("2006-01-02")}, nil
+	}
+	if t, err := parseTimeLayouts(value, "15:04:05", "15:04"); err == nil {
+		if !hasTime {
+			return nil, fmt.Errorf("%s does not take a time", entityID)
+		}
+		return map[string]interface{}{"time": t.Format("15:04:05")}, nil
+	}
+	return nil, fmt.Errorf("invalid date/time %q", value)
+}
+
+func parseTimeLayouts(value string, layouts ...string) (time.Time, error) {
+	var err error
+	for _, layout := range layouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
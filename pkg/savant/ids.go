@@ -0,0 +1,54 @@
+package savant
+
+import "strings"
+
+// allArgs marks a command whose arguments are all entity IDs.
+const allArgs = -1
+
+// entityArgs lists the argument positions holding entity IDs for commands
+// that differ from the usual "entity first" layout. Commands not listed
+// here have a single entity ID in args[0].
+var entityArgs = map[string][]int{
+	// call_service,domain,service,entity_id[,entity_id=...]
+	"call_service": {2},
+	// tts_speak,tts_entity,media_player_entity,message
+	"tts_speak": {0, 1},
+	// media_player_join,leader,member1[,member2...]
+	"media_player_join": {allArgs},
+	"subscribe_entity":  {allArgs},
+	// notify,service,... takes a notify service name, not an entity
+	"notify":           {},
+	"subscribe_events": {},
+	"list_areas":       {},
+	"list_devices":     {},
+	"list_entities":    {},
+}
+
+// resolveArgs rewrites substitute IDs in the entity arguments of cmd to
+// real HA entity IDs in place. "|"-separated lists are resolved element
+// by element, as are entity_id=... values passed to call_service.
+func (s *Server) resolveArgs(cmd string, args []string) {
+	positions, ok := entityArgs[cmd]
+	if !ok {
+		positions = []int{0}
+	}
+	for _, i := range positions {
+		if i == allArgs {
+			for j := range args {
+				args[j] = s.haClient.ResolveList(args[j])
+			}
+			break
+		}
+		if i < len(args) {
+			args[i] = s.haClient.ResolveList(args[i])
+		}
+	}
+
+	if cmd == "call_service" && len(args) > 3 {
+		for j, kv := range args[3:] {
+			if ids, ok := strings.CutPrefix(kv, "entity_id="); ok {
+				args[3+j] = "entity_id=" + s.haClient.ResolveList(ids)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package savant
+
+import (
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/metrics"
+)
+
+// serverMetrics are the instruments a Server updates as it runs.
+type serverMetrics struct {
+	commands  *metrics.CounterVec // by command name
+	broadcast metrics.Counter
+	dropped   metrics.Counter
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{commands: metrics.NewCounterVec("command")}
+}
+
+// RegisterMetrics adds the server's instruments to r.
+func (s *Server) RegisterMetrics(r *metrics.Registry) {
+	r.Register("bridge_savant_clients", "Connected Savant clients.", metrics.GaugeFunc(func() float64 {
+		s.clientsMu.Lock()
+		defer s.clientsMu.Unlock()
+		return float64(len(s.clients))
+	}))
+	r.Register("bridge_savant_commands_total", "Commands received from Savant, by name.", s.stats.commands)
+	r.Register("bridge_savant_lines_broadcast_total", "Lines broadcast to Savant clients.", &s.stats.broadcast)
+	r.Register("bridge_savant_dropped_lines_total", "Lines that could not be written to a Savant client.", &s.stats.dropped)
+}
+
+// countCommand counts a received command. Unknown names share one label so
+// that a misbehaving client cannot grow the metric without bound.
+func (s *Server) countCommand(cmd string) {
+	if _, ok := LookupCommand(cmd); !ok {
+		cmd = "unknown"
+	}
+	s.stats.commands.With(cmd).Inc()
+}
@@ -0,0 +1,24 @@
+package savant
+
+// paginate picks one page of a list of total rows for the list_* and
+// browse_media replies. The requested page is clamped to 1..pages, and an
+// empty list has a single empty page. The page's rows are [start, end).
+func paginate(total, size, page int) (current, pages, start, end int) {
+	pages = (total + size - 1) / size
+	if pages == 0 {
+		pages = 1
+	}
+	current = page
+	if current < 1 {
+		current = 1
+	}
+	if current > pages {
+		current = pages
+	}
+	start = (current - 1) * size
+	end = start + size
+	if end > total {
+		end = total
+	}
+	return current, pages, start, end
+}
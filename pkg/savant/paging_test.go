@@ -0,0 +1,59 @@
+package savant
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		total, size, page          int
+		current, pages, start, end int
+	}{
+		{0, 50, 1, 1, 1, 0, 0},
+		{0, 50, 3, 1, 1, 0, 0},
+		{10, 50, 1, 1, 1, 0, 10},
+		{50, 50, 2, 1, 1, 0, 50},
+		{51, 50, 2, 2, 2, 50, 51},
+		{45, 20, 0, 1, 3, 0, 20},
+		{45, 20, -4, 1, 3, 0, 20},
+		{45, 20, 3, 3, 3, 40, 45},
+		{45, 20, 9, 3, 3, 40, 45},
+	}
+	for _, tt := range tests {
+		current, pages, start, end := paginate(tt.total, tt.size, tt.page)
+		if current != tt.current || pages != tt.pages || start != tt.start || end != tt.end {
+			t.Errorf("paginate(%d, %d, %d) = %d, %d, %d, %d, want %d, %d, %d, %d",
+				tt.total, tt.size, tt.page, current, pages, start, end, tt.current, tt.pages, tt.start, tt.end)
+		}
+	}
+}
+
+func TestBrowseReplyGoesToAsker(t *testing.T) {
+	s, _ := newTestServer(t, allPolicies)
+
+	asker, askerPeer := net.Pipe()
+	other, otherPeer := net.Pipe()
+	defer asker.Close()
+	defer other.Close()
+	s.clients[asker] = "10.0.0.1"
+	s.clients[other] = "10.0.0.2"
+
+	go s.handleCommand(asker, "media_player_browse_media,area:den")
+
+	askerPeer.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := bufio.NewReader(askerPeer).ReadString('\n')
+	if err != nil {
+		t.Fatalf("asker got no reply: %v", err)
+	}
+	if want := "browse_media_error,area:den,a single media player is required\n"; line != want {
+		t.Errorf("asker got %q, want %q", line, want)
+	}
+
+	otherPeer.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, _ := otherPeer.Read(make([]byte, 1)); n != 0 {
+		t.Error("another client received the reply")
+	}
+}
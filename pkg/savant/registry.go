@@ -0,0 +1,114 @@
+package savant
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+)
+
+const registryPageSize = 50
+
+// The list_* commands export the HA registries so that Savant data tables
+// can be filled without copying IDs out of the HA UI. Each reply is one
+// page of rows:
+//
+//	list_areas,<page>,<pages>,<total>
+//	list_areas_item,<index>,<area_id>,<name>,<floor_id>
+//	list_areas_end
+//
+//	list_devices_item,<index>,<device_id>,<name>,<area_id>,<manufacturer>,<model>
+//	list_entities_item,<index>,<entity_id>,<name>,<area_id>,<device_id>,<supported_features>
+//
+// list_devices and list_entities use the same header and end lines. Errors
+// are reported as <cmd>_error,<message>. Replies go to the asking client
+// only.
+
+// listAreas handles list_areas[,page].
+func (s *Server) listAreas(conn net.Conn, args []string) {
+	page := listPage(args)
+	s.haClient.ListAreas(func(areas []ha.Area, err error) {
+		if err != nil {
+			s.sendListError(conn, "list_areas", err)
+			return
+		}
+		rows := make([][]string, len(areas))
+		for i, a := range areas {
+			rows[i] = []string{a.ID, a.Name, a.FloorID}
+		}
+		s.sendList(conn, "list_areas", rows, page)
+	})
+}
+
+// listDevices handles list_devices[,page].
+func (s *Server) listDevices(conn net.Conn, args []string) {
+	page := listPage(args)
+	s.haClient.ListDevices(func(devices []ha.Device, err error) {
+		if err != nil {
+			s.sendListError(conn, "list_devices", err)
+			return
+		}
+		rows := make([][]string, len(devices))
+		for i, d := range devices {
+			rows[i] = []string{d.ID, d.Name, d.AreaID, d.Manufacturer, d.Model}
+		}
+		s.sendList(conn, "list_devices", rows, page)
+	})
+}
+
+// listEntities handles list_entities[,domain][,page].
+func (s *Server) listEntities(conn net.Conn, args []string) {
+	domain := ""
+	if len(args) > 0 {
+		if _, err := strconv.Atoi(args[0]); err != nil {
+			domain = strings.TrimSpace(args[0])
+			args = args[1:]
+		}
+	}
+	page := listPage(args)
+	s.haClient.ListEntities(domain, func(entities []ha.RegistryEntity, err error) {
+		if err != nil {
+			s.sendListError(conn, "list_entities", err)
+			return
+		}
+		rows := make([][]string, len(entities))
+		for i, e := range entities {
+			rows[i] = []string{e.EntityID, e.Name, e.AreaID, e.DeviceID, strconv.Itoa(e.SupportedFeatures)}
+		}
+		s.sendList(conn, "list_entities", rows, page)
+	})
+}
+
+func listPage(args []string) int {
+	if len(args) > 0 {
+		if page, err := strconv.Atoi(args[0]); err == nil {
+			return page
+		}
+	}
+	return 1
+}
+
+func (s *Server) sendList(conn net.Conn, cmd string, rows [][]string, page int) {
+	page, pages, start, end := paginate(len(rows), registryPageSize, page)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s,%d,%d,%d\n", cmd, page, pages, len(rows))
+	for i := start; i < end; i++ {
+		fmt.Fprintf(&b, "%s_item,%d", cmd, i)
+		for _, field := range rows[i] {
+			b.WriteString(",")
+			b.WriteString(quoteField(field))
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%s_end\n", cmd)
+	s.reply(conn, b.String())
+}
+
+func (s *Server) sendListError(conn net.Conn, cmd string, err error) {
+	log.Printf("%s failed: %v", cmd, err)
+	s.reply(conn, fmt.Sprintf("%s_error,%s\n", cmd, quoteField(err.Error())))
+}
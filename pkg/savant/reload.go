@@ -0,0 +1,87 @@
+package savant
+
+import (
+	"log"
+	"net"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+)
+
+// settings are the options the server can pick up without a restart. A
+// new value is swapped in as a whole on reload, so a command always sees
+// one consistent configuration.
+type settings struct {
+	whitelist        []string
+	shadeOverrides   map[string]config.ShadeOverride
+	alarmDefaultCode string
+	checkCodeFormat  bool
+	lockCodes        map[string]string
+	policy           config.Policy
+	cameras          map[string]bool
+	disabled         map[string]bool // commands refused by disabled_commands
+}
+
+func newSettings(cfg *config.Config) *settings {
+	disabled := make(map[string]bool)
+	for _, name := range cfg.Options.DisabledCommands {
+		if _, ok := LookupCommand(name); !ok {
+			log.Printf("Savant: disabled_commands: unknown command %q", name)
+			continue
+		}
+		disabled[name] = true
+	}
+	return &settings{
+		whitelist:        cfg.Whitelist,
+		shadeOverrides:   cfg.ShadeOverrides,
+		alarmDefaultCode: cfg.Options.AlarmCode,
+		checkCodeFormat:  cfg.Options.AlarmCheckCodeFormat,
+		lockCodes:        cfg.LockCodes,
+		policy:           cfg.Policy,
+		cameras:          cfg.Cameras,
+		disabled:         disabled,
+	}
+}
+
+func (s *Server) current() *settings {
+	return s.settings.Load()
+}
+
+// allows reports whether a client IP passes the whitelist.
+func (st *settings) allows(ip string) bool {
+	if len(st.whitelist) == 0 {
+		return true
+	}
+	for _, allowed := range st.whitelist {
+		if allowed == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload applies a new configuration. Clients whose address is no longer
+// whitelisted are disconnected; everyone else stays connected.
+func (s *Server) Reload(cfg *config.Config) {
+	st := newSettings(cfg)
+	s.settings.Store(st)
+
+	var dropped []net.Conn
+	s.clientsMu.Lock()
+	for conn, ip := range s.clients {
+		if !st.allows(ip) {
+			dropped = append(dropped, conn)
+		}
+	}
+	s.clientsMu.Unlock()
+
+	for _, conn := range dropped {
+		log.Printf("Savant: Closing connection from %s, no longer whitelisted", s.clientIP(conn))
+		conn.Close()
+	}
+}
+
+func (s *Server) clientIP(conn net.Conn) string {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	return s.clients[conn]
+}
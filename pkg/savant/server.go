@@ -2,30 +2,51 @@ package savant
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
 	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/logging"
 )
 
 type Server struct {
-	port      int
-	whitelist []string
-	haClient  *ha.Client
-	clients   map[net.Conn]bool
+	port        int
+	settings    atomic.Pointer[settings] // replaced on reload
+	httpBaseURL string
+	haClient    *ha.Client
+
+	// clientsMu protects clients, listener and closing.
+	clientsMu sync.Mutex
+	clients   map[net.Conn]string // conn -> remote IP
+	listener  net.Listener
+	closing   bool
+	inflight  sync.WaitGroup // commands being handled
+
+	stats *serverMetrics
 }
 
 func NewServer(port int, cfg *config.Config, haClient *ha.Client) *Server {
-	return &Server{
-		port:      port,
-		whitelist: cfg.Whitelist,
-		haClient:  haClient,
-		clients:   make(map[net.Conn]bool),
+	s := &Server{
+		port:     port,
+		haClient: haClient,
+		clients:  make(map[net.Conn]string),
+		stats:    newServerMetrics(),
 	}
+	s.settings.Store(newSettings(cfg))
+	return s
+}
+
+// SetHTTPBaseURL tells the server where the bridge's HTTP endpoint is, so
+// that commands such as camera_snapshot can hand out URLs.
+func (s *Server) SetHTTPBaseURL(url string) {
+	s.httpBaseURL = url
 }
 
 func (s *Server) Start() {
@@ -36,9 +57,16 @@ func (s *Server) Start() {
 	}
 	log.Printf("Savant: Listening on %s", addr)
 
+	s.clientsMu.Lock()
+	s.listener = listener
+	s.clientsMu.Unlock()
+
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if s.isClosing() {
+				return
+			}
 			log.Printf("Savant: Accept error: %v", err)
 			continue
 		}
@@ -47,9 +75,32 @@ func (s *Server) Start() {
 }
 
 func (s *Server) Broadcast(msg string) {
+	logging.Debugf("Savant: -> %s", strings.TrimRight(msg, "\n"))
+	s.clientsMu.Lock()
+	conns := make([]net.Conn, 0, len(s.clients))
 	for conn := range s.clients {
+		conns = append(conns, conn)
+	}
+	s.clientsMu.Unlock()
+
+	s.stats.broadcast.Inc()
+	for _, conn := range conns {
 		// Ignore errors on broadcast, handle in connection loop
-		conn.Write([]byte(msg))
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			s.stats.dropped.Inc()
+		}
+	}
+}
+
+// reply sends msg to one client, for answers such as list pages that are
+// of no use to the other Savant hosts. Nothing is sent when conn is nil.
+func (s *Server) reply(conn net.Conn, msg string) {
+	logging.Debugf("Savant: -> %s", strings.TrimRight(msg, "\n"))
+	if conn == nil {
+		return
+	}
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		s.stats.dropped.Inc()
 	}
 }
 
@@ -58,45 +109,52 @@ func (s *Server) handleConnection(conn net.Conn) {
 	remoteAddr := conn.RemoteAddr().(*net.TCPAddr).IP.String()
 
 	// 1. Whitelist Check
-	allowed := false
-	if len(s.whitelist) == 0 {
-		allowed = true
-	} else {
-		for _, ip := range s.whitelist {
-			if ip == remoteAddr {
-				allowed = true
-				break
-			}
-		}
-	}
-
-	if !allowed {
+	if !s.current().allows(remoteAddr) {
 		log.Printf("Savant: Access denied for %s", remoteAddr)
 		return
 	}
 
+	s.clientsMu.Lock()
+	if s.closing {
+		s.clientsMu.Unlock()
+		return
+	}
+	s.clients[conn] = remoteAddr
+	s.clientsMu.Unlock()
 	log.Printf("Savant: Client connected %s", remoteAddr)
-	s.clients[conn] = true
-	defer delete(s.clients, conn)
+	defer func() {
+		s.clientsMu.Lock()
+		delete(s.clients, conn)
+		s.clientsMu.Unlock()
+		log.Printf("Savant: Client disconnected %s", remoteAddr)
+	}()
 
 	// 2. Read Loop
 	scanner := bufio.NewScanner(conn)
 	for scanner.Scan() {
+		if !s.beginCommand() {
+			return
+		}
 		text := scanner.Text()
-		s.handleCommand(text)
+		s.handleCommand(conn, text)
+		s.inflight.Done()
 	}
 }
 
-func (s *Server) handleCommand(cmdStr string) {
+// handleCommand runs one command line from conn. Most commands only act on
+// HA; replies meant for the asking client alone are written to conn.
+func (s *Server) handleCommand(conn net.Conn, cmdStr string) {
 	// Savant sends commands separated by commas
 	// Example: switch_on,light.living_room
-	parts := strings.Split(cmdStr, ",")
+	// Free text can be quoted: notify,mobile_app_phone,Doorbell,"Someone is at the door, front"
+	parts := splitArgs(cmdStr)
 	if len(parts) == 0 {
 		return
 	}
 
 	cmd := parts[0]
 	args := parts[1:]
+	s.countCommand(cmd)
 
 	// Handle special setup commands that don't use entity IDs
 	if cmd == "substitute_ids" {
@@ -104,7 +162,7 @@ func (s *Server) handleCommand(cmdStr string) {
 		subs := make(map[string]string)
 		var haIDs []string
 		for i := 0; i < len(args); i += 2 {
-			if i+1 < len(args) {
+			if i+1 < len(args) && args[i+1] != "" {
 				// key (savant id) -> value (ha entity id)
 				subs[args[i+1]] = args[i]
 				haIDs = append(haIDs, args[i+1])
@@ -124,27 +182,30 @@ func (s *Server) handleCommand(cmdStr string) {
 	
 	if cmd == "subscribe_entity" {
 		// args are entity_ids
+		s.resolveArgs(cmd, args)
 		s.haClient.SubscribeEntities(args)
 		return
 	}
 
-	// For other commands, the first arg is usually entity_id.
-	// We need to resolve it if it's a substitute ID.
-	if len(args) > 0 {
-		// We need a way to resolve substitute ID to real ID.
-		// The HA Client has this mapping. Let's expose it or pass it.
-		// Actually, Client.GetEntityID is implemented as ha_id -> sub_id?
-		// Wait, Client.GetEntityID implementation was: 
-		// if realID, ok := c.substituteIDs[id]; ok { return realID }
-		// But substituteIDs was map[ha_id]sub_id.
-		// So c.substituteIDs[ha_id] returns sub_id.
-		// We need sub_id -> ha_id.
-		// I added idSubstitutes map[sub_id]ha_id in client.
-		// Let's update Client to expose a ResolveID method.
-		args[0] = s.haClient.ResolveID(args[0])
+	// Translate substitute IDs in every entity argument to real HA IDs
+	s.resolveArgs(cmd, args)
+
+	logging.Infof("Savant Command: %s %v", cmd, redactArgs(cmd, args))
+
+	spec, ok := LookupCommand(cmd)
+	if !ok {
+		log.Printf("Unknown command: %s", cmd)
+		return
+	}
+	if len(args) < spec.MinArgs {
+		log.Printf("Command %s expects at least %d arguments, got %d", cmd, spec.MinArgs, len(args))
+		return
 	}
 
-	log.Printf("Savant Command: %s %v", cmd, args)
+	if st := s.current(); !st.policy.Allows(cmd) || st.disabled[cmd] {
+		log.Printf("Command %s is disabled by configuration", cmd)
+		return
+	}
 
 	switch cmd {
 	case "subscribe_events":
@@ -168,8 +229,216 @@ func (s *Server) handleCommand(cmdStr string) {
 					}
 				}
 			}
+			if !s.serviceAllowed(domain, service, entityID, data) {
+				log.Printf("call_service %s.%s is disabled by configuration", domain, service)
+				return
+			}
 			s.callService(domain, service, entityID, data)
 		}
+	case "scene_activate":
+		// format: scene_activate,scene_id[,transition]
+		if len(args) > 0 {
+			data := map[string]interface{}{}
+			addTransition(data, args[1:])
+			s.callService("scene", "turn_on", args[0], data)
+		}
+	case "script_run":
+		// format: script_run,script_id,key1=value1,key2=value2...
+		if len(args) > 0 {
+			var data map[string]interface{}
+			if len(args) > 1 {
+				data = map[string]interface{}{"variables": parseKeyValues(args[1:])}
+			}
+			s.callService("script", "turn_on", args[0], data)
+		}
+	case "automation_trigger":
+		// format: automation_trigger,automation_id[,skip_condition]
+		if len(args) > 0 {
+			skip := true
+			if len(args) > 1 {
+				skip = strings.ToLower(args[1]) != "false"
+			}
+			s.callService("automation", "trigger", args[0], map[string]interface{}{"skip_condition": skip})
+		}
+	case "automation_toggle":
+		// format: automation_toggle,automation_id[,on|off]
+		if len(args) > 0 {
+			service := "toggle"
+			if len(args) > 1 {
+				switch strings.ToLower(args[1]) {
+				case "on", "enable", "true":
+					service = "turn_on"
+				case "off", "disable", "false":
+					service = "turn_off"
+				}
+			}
+			s.callService("automation", service, args[0], nil)
+		}
+	case "input_select_option":
+		// format: input_select_option,entity_id,option
+		if len(args) > 1 {
+			option := strings.Join(args[1:], ",")
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			for _, e := range entities {
+				if err := s.validateSelectOption(e, option); err != nil {
+					log.Printf("input_select_option: %v", err)
+					return
+				}
+			}
+			s.callService("input_select", "select_option", args[0], map[string]interface{}{"option": option})
+		}
+	case "input_select_next", "input_select_previous":
+		// format: input_select_next,entity_id[,cycle]
+		if len(args) > 0 {
+			service := strings.TrimPrefix(cmd, "input_")
+			cycle := true
+			if len(args) > 1 {
+				cycle = strings.ToLower(args[1]) != "false"
+			}
+			s.callService("input_select", service, args[0], map[string]interface{}{"cycle": cycle})
+		}
+	case "input_number_set":
+		// format: input_number_set,entity_id,value
+		if len(args) > 1 {
+			value, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				log.Printf("input_number_set: invalid value %q", args[1])
+				return
+			}
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			for _, e := range entities {
+				if err := s.validateNumber(e, value); err != nil {
+					log.Printf("input_number_set: %v", err)
+					return
+				}
+			}
+			s.callService("input_number", "set_value", args[0], map[string]interface{}{"value": value})
+		}
+	case "input_number_increment":
+		if len(args) > 0 {
+			s.callService("input_number", "increment", args[0], nil)
+		}
+	case "input_number_decrement":
+		if len(args) > 0 {
+			s.callService("input_number", "decrement", args[0], nil)
+		}
+	case "input_text_set":
+		// format: input_text_set,entity_id,text
+		if len(args) > 1 {
+			value := strings.Join(args[1:], ",")
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			for _, e := range entities {
+				if err := s.validateText(e, value); err != nil {
+					log.Printf("input_text_set: %v", err)
+					return
+				}
+			}
+			s.callService("input_text", "set_value", args[0], map[string]interface{}{"value": value})
+		}
+	case "input_boolean_on":
+		if len(args) > 0 {
+			s.callService("input_boolean", "turn_on", args[0], nil)
+		}
+	case "input_boolean_off":
+		if len(args) > 0 {
+			s.callService("input_boolean", "turn_off", args[0], nil)
+		}
+	case "input_boolean_toggle":
+		if len(args) > 0 {
+			s.callService("input_boolean", "toggle", args[0], nil)
+		}
+	case "input_datetime_set":
+		// format: input_datetime_set,entity_id,YYYY-MM-DD|HH:MM:SS|YYYY-MM-DD HH:MM:SS|timestamp
+		if len(args) > 1 {
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			// The data depends only on the value; each entity is checked
+			// for whether it takes a date and a time.
+			var data map[string]interface{}
+			for _, e := range entities {
+				var err error
+				if data, err = s.datetimeData(e, args[1]); err != nil {
+					log.Printf("input_datetime_set: %v", err)
+					return
+				}
+			}
+			s.callService("input_datetime", "set_datetime", args[0], data)
+		}
+	case "notify":
+		// format: notify,service,title,message[,key=value...]
+		// service is notify.<name> or just <name>; title may be empty.
+		if len(args) > 2 {
+			service := strings.TrimPrefix(args[0], "notify.")
+			data := map[string]interface{}{"message": args[2]}
+			if args[1] != "" {
+				data["title"] = args[1]
+			}
+			if len(args) > 3 {
+				data["data"] = parseKeyValues(args[3:])
+			}
+			s.callService("notify", service, "", data)
+		}
+	case "tts_speak":
+		// format: tts_speak,tts_entity,media_player_entity,message[,language][,key=value...]
+		// key=value pairs become TTS options, except cache which is passed as is.
+		if len(args) > 2 {
+			data := map[string]interface{}{
+				"media_player_entity_id": args[1],
+				"message":                args[2],
+			}
+			if players, _ := targetEntities(args[1]); len(players) > 1 {
+				data["media_player_entity_id"] = players
+			}
+			rest := args[3:]
+			if len(rest) > 0 && !strings.Contains(rest[0], "=") {
+				if rest[0] != "" {
+					data["language"] = rest[0]
+				}
+				rest = rest[1:]
+			}
+			options := parseKeyValues(rest)
+			if cache, ok := options["cache"]; ok {
+				data["cache"] = cache
+				delete(options, "cache")
+			}
+			if len(options) > 0 {
+				data["options"] = options
+			}
+			s.callService("tts", "speak", args[0], data)
+		}
+	case "camera_snapshot":
+		// Replies with camera_snapshot,<entity_id>,<url> for Savant to poll,
+		// or camera_snapshot_error,<entity_id>,<reason>.
+		// A list of cameras gets one reply per camera.
+		if len(args) > 0 {
+			entities, ok := targetEntities(args[0])
+			if !ok {
+				s.Broadcast(fmt.Sprintf("camera_snapshot_error,%s,camera entity required\n", s.haClient.OutboundID(args[0])))
+				return
+			}
+			for _, e := range entities {
+				id := s.haClient.OutboundID(e)
+				switch {
+				case s.httpBaseURL == "":
+					s.Broadcast(fmt.Sprintf("camera_snapshot_error,%s,http endpoint disabled\n", id))
+				case !s.current().cameras[e]:
+					s.Broadcast(fmt.Sprintf("camera_snapshot_error,%s,camera not configured\n", id))
+				default:
+					s.Broadcast(fmt.Sprintf("camera_snapshot,%s,%s/camera/%s\n", id, s.httpBaseURL, e))
+				}
+			}
+		}
 	case "fan_on":
 		if len(args) > 1 {
 			s.callService("fan", "turn_on", args[0], map[string]interface{}{"speed": args[1]})
@@ -190,23 +459,28 @@ func (s *Server) handleCommand(cmdStr string) {
 		if len(args) > 0 {
 			s.callService("button", "press", args[0], nil)
 		}
-	case "alarm_arm_away":
-		if len(args) > 0 {
-			data := map[string]interface{}{}
-			if len(args) > 1 { data["code"] = args[1] }
-			s.callService("alarm_control_panel", "alarm_arm_away", args[0], data)
-		}
-	case "alarm_arm_home":
+	case "alarm_arm_away", "alarm_arm_home", "alarm_arm_night", "alarm_arm_vacation",
+		"alarm_arm_custom_bypass", "alarm_disarm", "alarm_trigger":
+		// format: <command>,entity_id[,code]
+		// The HA service names match the command names. Codes are per
+		// panel, so a list of panels is called one by one.
 		if len(args) > 0 {
-			data := map[string]interface{}{}
-			if len(args) > 1 { data["code"] = args[1] }
-			s.callService("alarm_control_panel", "alarm_arm_home", args[0], data)
-		}
-	case "alarm_disarm":
-		if len(args) > 0 {
-			data := map[string]interface{}{}
-			if len(args) > 1 { data["code"] = args[1] }
-			s.callService("alarm_control_panel", "alarm_disarm", args[0], data)
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			for _, e := range entities {
+				code, err := s.alarmCode(cmd, e, args)
+				if err != nil {
+					log.Printf("%s rejected for %s: %v", cmd, e, err)
+					continue
+				}
+				data := map[string]interface{}{}
+				if code != "" {
+					data["code"] = code
+				}
+				s.callService("alarm_control_panel", cmd, e, data)
+			}
 		}
 	case "remote_on":
 		if len(args) > 0 {
@@ -222,11 +496,19 @@ func (s *Server) handleCommand(cmdStr string) {
 		}
 	case "switch_on":
 		if len(args) > 0 {
-			s.callService("light", "turn_on", args[0], nil)
+			s.onOff(cmd, onOffDomain(args[0]), "turn_on", args[0])
 		}
 	case "switch_off":
 		if len(args) > 0 {
-			s.callService("light", "turn_off", args[0], nil)
+			s.onOff(cmd, onOffDomain(args[0]), "turn_off", args[0])
+		}
+	case "toggle":
+		if len(args) > 0 {
+			s.onOff(cmd, onOffDomain(args[0]), "toggle", args[0])
+		}
+	case "homeassistant_toggle":
+		if len(args) > 0 {
+			s.onOff(cmd, "homeassistant", "toggle", args[0])
 		}
 	case "socket_on":
 		if len(args) > 0 {
@@ -237,18 +519,119 @@ func (s *Server) handleCommand(cmdStr string) {
 			s.callService("switch", "turn_off", args[0], nil)
 		}
 	case "dimmer_set":
+		// format: dimmer_set,entity_id,level[,transition]
 		if len(args) > 1 {
 			level, _ := strconv.Atoi(args[1])
+			data := map[string]interface{}{}
+			addTransition(data, args[2:])
 			if level == 0 {
-				s.callService("light", "turn_off", args[0], nil)
+				s.callService("light", "turn_off", args[0], data)
 			} else {
-				s.callService("light", "turn_on", args[0], map[string]interface{}{"brightness_pct": level})
+				data["brightness_pct"] = level
+				s.callService("light", "turn_on", args[0], data)
 			}
 		}
+	case "light_hs_color":
+		// format: light_hs_color,entity_id,hue(0-255),sat(0-255)[,transition]
+		if len(args) > 2 {
+			hs, err := savantHSToHA(args[1], args[2])
+			if err != nil {
+				log.Printf("light_hs_color: %v", err)
+				return
+			}
+			data := map[string]interface{}{"hs_color": hs}
+			addTransition(data, args[3:])
+			s.callService("light", "turn_on", args[0], data)
+		}
+	case "light_rgb_color", "light_rgbw_color":
+		// format: light_rgb_color,entity_id,RRGGBB|r,g,b[,transition]
+		//         light_rgbw_color,entity_id,RRGGBBWW|r,g,b,w[,transition]
+		if len(args) > 1 {
+			attr, channels := "rgb_color", 3
+			if cmd == "light_rgbw_color" {
+				attr, channels = "rgbw_color", 4
+			}
+			color, n, err := parseColorArgs(args[1:], channels)
+			if err != nil {
+				log.Printf("%s: %v", cmd, err)
+				return
+			}
+			data := map[string]interface{}{attr: color}
+			addTransition(data, args[1+n:])
+			s.callService("light", "turn_on", args[0], data)
+		}
+	case "light_color_temp_kelvin":
+		// format: light_color_temp_kelvin,entity_id,kelvin[,transition]
+		if len(args) > 1 {
+			kelvin, err := strconv.Atoi(args[1])
+			if err != nil || kelvin <= 0 {
+				log.Printf("light_color_temp_kelvin: invalid kelvin value %q", args[1])
+				return
+			}
+			data := map[string]interface{}{"color_temp_kelvin": kelvin}
+			addTransition(data, args[2:])
+			s.callService("light", "turn_on", args[0], data)
+		}
+	case "light_color_temp_mired":
+		// format: light_color_temp_mired,entity_id,mired[,transition]
+		if len(args) > 1 {
+			kelvin, err := miredToKelvin(args[1])
+			if err != nil {
+				log.Printf("light_color_temp_mired: %v", err)
+				return
+			}
+			data := map[string]interface{}{"color_temp_kelvin": kelvin}
+			addTransition(data, args[2:])
+			s.callService("light", "turn_on", args[0], data)
+		}
+	case "light_effect":
+		if len(args) > 1 {
+			s.callService("light", "turn_on", args[0], map[string]interface{}{"effect": args[1]})
+		}
+	case "light_flash":
+		// format: light_flash,entity_id[,short|long]
+		if len(args) > 0 {
+			flash := "short"
+			if len(args) > 1 && args[1] == "long" {
+				flash = "long"
+			}
+			s.callService("light", "turn_on", args[0], map[string]interface{}{"flash": flash})
+		}
 	case "shade_set":
 		if len(args) > 1 {
 			pos, _ := strconv.Atoi(args[1])
-			s.callService("cover", "set_cover_position", args[0], map[string]interface{}{"position": pos})
+			s.moveShades(args[0], pos, "set_cover_position")
+		}
+	case "shade_open":
+		if len(args) > 0 {
+			// An inverted shade reports "open" at HA position 0, so map
+			// through the override instead of calling open_cover.
+			s.moveShades(args[0], 100, "open_cover")
+		}
+	case "shade_close":
+		if len(args) > 0 {
+			s.moveShades(args[0], 0, "close_cover")
+		}
+	case "shade_stop", "stop_garage_door":
+		if len(args) > 0 {
+			s.callService("cover", "stop_cover", args[0], nil)
+		}
+	case "shade_tilt_set":
+		if len(args) > 1 {
+			pos, _ := strconv.Atoi(args[1])
+			s.callService("cover", "set_cover_tilt_position", args[0], map[string]interface{}{"tilt_position": pos})
+		}
+	case "shade_tilt_open":
+		if len(args) > 0 {
+			s.callService("cover", "open_cover_tilt", args[0], nil)
+		}
+	case "shade_tilt_close":
+		if len(args) > 0 {
+			s.callService("cover", "close_cover_tilt", args[0], nil)
+		}
+	case "shade_tilt_stop":
+		if len(args) > 0 {
+			s.callService("cover", "stop_cover_tilt", args[0], nil)
 		}
 	case "open_garage_door":
 		if len(args) > 0 {
@@ -262,13 +645,26 @@ func (s *Server) handleCommand(cmdStr string) {
 		if len(args) > 0 {
 			s.callService("cover", "toggle", args[0], nil)
 		}
-	case "lock_lock":
+	case "lock_lock", "unlock_lock", "lock_open":
+		// format: <command>,entity_id[,code]
+		// Codes are per lock, so a list of locks is called one by one.
 		if len(args) > 0 {
-			s.callService("lock", "lock", args[0], nil)
-		}
-	case "unlock_lock":
-		if len(args) > 0 {
-			s.callService("lock", "unlock", args[0], nil)
+			service := map[string]string{
+				"lock_lock":   "lock",
+				"unlock_lock": "unlock",
+				"lock_open":   "open",
+			}[cmd]
+			entities, ok := entityList(cmd, args[0])
+			if !ok {
+				return
+			}
+			for _, e := range entities {
+				var data map[string]interface{}
+				if code := s.lockCode(e, args); code != "" {
+					data = map[string]interface{}{"code": code}
+				}
+				s.callService("lock", service, e, data)
+			}
 		}
 	case "climate_set_hvac_mode":
 		if len(args) > 1 {
@@ -277,6 +673,7 @@ func (s *Server) handleCommand(cmdStr string) {
 	case "climate_set_single":
 		if len(args) > 1 {
 			temp, _ := strconv.ParseFloat(args[1], 64)
+			temp = s.haClient.ToHATemperature(temp)
 			s.callService("climate", "set_temperature", args[0], map[string]interface{}{"temperature": temp})
 		}
 	case "climate_set_temperature_range":
@@ -284,10 +681,160 @@ func (s *Server) handleCommand(cmdStr string) {
 			low, _ := strconv.ParseFloat(args[1], 64)
 			high, _ := strconv.ParseFloat(args[2], 64)
 			s.callService("climate", "set_temperature", args[0], map[string]interface{}{
-				"target_temp_low":  low,
-				"target_temp_high": high,
+				"target_temp_low":  s.haClient.ToHATemperature(low),
+				"target_temp_high": s.haClient.ToHATemperature(high),
 			})
 		}
+	case "climate_set_fan_mode":
+		if len(args) > 1 {
+			s.callService("climate", "set_fan_mode", args[0], map[string]interface{}{"fan_mode": args[1]})
+		}
+	case "climate_set_preset_mode":
+		if len(args) > 1 {
+			s.callService("climate", "set_preset_mode", args[0], map[string]interface{}{"preset_mode": args[1]})
+		}
+	case "climate_set_swing_mode":
+		if len(args) > 1 {
+			s.callService("climate", "set_swing_mode", args[0], map[string]interface{}{"swing_mode": args[1]})
+		}
+	case "climate_set_humidity":
+		if len(args) > 1 {
+			humidity, _ := strconv.Atoi(args[1])
+			s.callService("climate", "set_humidity", args[0], map[string]interface{}{"humidity": humidity})
+		}
+	case "climate_turn_on":
+		if len(args) > 0 {
+			s.callService("climate", "turn_on", args[0], nil)
+		}
+	case "climate_turn_off":
+		if len(args) > 0 {
+			s.callService("climate", "turn_off", args[0], nil)
+		}
+	case "climate_set_aux_heat":
+		// format: climate_set_aux_heat,entity_id,on|off
+		if len(args) > 1 {
+			on := strings.ToLower(args[1]) == "on" || strings.ToLower(args[1]) == "true" || args[1] == "1"
+			s.callService("climate", "set_aux_heat", args[0], map[string]interface{}{"aux_heat": on})
+		}
+	case "vacuum_start":
+		if len(args) > 0 {
+			s.callService("vacuum", "start", args[0], nil)
+		}
+	case "vacuum_pause":
+		if len(args) > 0 {
+			s.callService("vacuum", "pause", args[0], nil)
+		}
+	case "vacuum_stop":
+		if len(args) > 0 {
+			s.callService("vacuum", "stop", args[0], nil)
+		}
+	case "vacuum_return_to_base":
+		if len(args) > 0 {
+			s.callService("vacuum", "return_to_base", args[0], nil)
+		}
+	case "vacuum_locate":
+		if len(args) > 0 {
+			s.callService("vacuum", "locate", args[0], nil)
+		}
+	case "vacuum_clean_spot":
+		if len(args) > 0 {
+			s.callService("vacuum", "clean_spot", args[0], nil)
+		}
+	case "vacuum_set_fan_speed":
+		if len(args) > 1 {
+			s.callService("vacuum", "set_fan_speed", args[0], map[string]interface{}{"fan_speed": args[1]})
+		}
+	case "vacuum_send_command":
+		// format: vacuum_send_command,entity_id,command[,key=value...]
+		if len(args) > 1 {
+			data := map[string]interface{}{"command": args[1]}
+			if len(args) > 2 {
+				data["params"] = parseKeyValues(args[2:])
+			}
+			s.callService("vacuum", "send_command", args[0], data)
+		}
+	case "lawn_mower_start":
+		if len(args) > 0 {
+			s.callService("lawn_mower", "start_mowing", args[0], nil)
+		}
+	case "lawn_mower_pause":
+		if len(args) > 0 {
+			s.callService("lawn_mower", "pause", args[0], nil)
+		}
+	case "lawn_mower_dock":
+		if len(args) > 0 {
+			s.callService("lawn_mower", "dock", args[0], nil)
+		}
+	case "valve_open":
+		if len(args) > 0 {
+			s.callService("valve", "open_valve", args[0], nil)
+		}
+	case "valve_close":
+		if len(args) > 0 {
+			s.callService("valve", "close_valve", args[0], nil)
+		}
+	case "valve_stop":
+		if len(args) > 0 {
+			s.callService("valve", "stop_valve", args[0], nil)
+		}
+	case "valve_toggle":
+		if len(args) > 0 {
+			s.callService("valve", "toggle", args[0], nil)
+		}
+	case "valve_set":
+		if len(args) > 1 {
+			pos, _ := strconv.Atoi(args[1])
+			s.callService("valve", "set_valve_position", args[0], map[string]interface{}{"position": pos})
+		}
+	case "water_heater_set_temperature":
+		// format: water_heater_set_temperature,entity_id,temperature[,operation_mode]
+		if len(args) > 1 {
+			temp, _ := strconv.ParseFloat(args[1], 64)
+			data := map[string]interface{}{"temperature": s.haClient.ToHATemperature(temp)}
+			if len(args) > 2 && args[2] != "" {
+				data["operation_mode"] = args[2]
+			}
+			s.callService("water_heater", "set_temperature", args[0], data)
+		}
+	case "water_heater_set_operation_mode":
+		if len(args) > 1 {
+			s.callService("water_heater", "set_operation_mode", args[0], map[string]interface{}{"operation_mode": args[1]})
+		}
+	case "water_heater_set_away_mode":
+		// format: water_heater_set_away_mode,entity_id,on|off
+		if len(args) > 1 {
+			away := strings.ToLower(args[1]) == "on" || strings.ToLower(args[1]) == "true"
+			s.callService("water_heater", "set_away_mode", args[0], map[string]interface{}{"away_mode": away})
+		}
+	case "water_heater_turn_on":
+		if len(args) > 0 {
+			s.callService("water_heater", "turn_on", args[0], nil)
+		}
+	case "water_heater_turn_off":
+		if len(args) > 0 {
+			s.callService("water_heater", "turn_off", args[0], nil)
+		}
+	case "humidifier_on":
+		if len(args) > 0 {
+			s.callService("humidifier", "turn_on", args[0], nil)
+		}
+	case "humidifier_off":
+		if len(args) > 0 {
+			s.callService("humidifier", "turn_off", args[0], nil)
+		}
+	case "humidifier_toggle":
+		if len(args) > 0 {
+			s.callService("humidifier", "toggle", args[0], nil)
+		}
+	case "humidifier_set_humidity":
+		if len(args) > 1 {
+			humidity, _ := strconv.Atoi(args[1])
+			s.callService("humidifier", "set_humidity", args[0], map[string]interface{}{"humidity": humidity})
+		}
+	case "humidifier_set_mode":
+		if len(args) > 1 {
+			s.callService("humidifier", "set_mode", args[0], map[string]interface{}{"mode": args[1]})
+		}
 	case "media_player_play":
 		if len(args) > 0 {
 			s.callService("media_player", "media_play", args[0], nil)
@@ -347,33 +894,168 @@ func (s *Server) handleCommand(cmdStr string) {
 			s.callService("media_player", "media_seek", args[0], map[string]interface{}{"seek_position": pos})
 		}
 	case "media_player_play_media":
-		// Expects json params in second arg? Ruby: JSON.parse(json_params)
-		// Go simplistic approach: assume it might be content_id, content_type
-		// Or if args[1] is JSON string.
+		// format: media_player_play_media,entity_id,content_type,content_id[,enqueue]
+		//     or: media_player_play_media,entity_id,{json service data}
+		if len(args) > 1 {
+			var data map[string]interface{}
+			if strings.HasPrefix(strings.TrimSpace(args[1]), "{") {
+				raw := strings.Join(args[1:], ",")
+				if err := json.Unmarshal([]byte(raw), &data); err != nil {
+					log.Printf("media_player_play_media: invalid JSON %q: %v", raw, err)
+					return
+				}
+			} else if len(args) > 2 {
+				data = map[string]interface{}{
+					"media_content_type": args[1],
+					"media_content_id":   args[2],
+				}
+				if len(args) > 3 && args[3] != "" {
+					data["enqueue"] = args[3]
+				}
+			} else {
+				return
+			}
+			s.callService("media_player", "play_media", args[0], data)
+		}
+	case "media_player_on":
+		if len(args) > 0 {
+			s.callService("media_player", "turn_on", args[0], nil)
+		}
+	case "media_player_off":
+		if len(args) > 0 {
+			s.callService("media_player", "turn_off", args[0], nil)
+		}
+	case "media_player_volume_mute":
+		// format: media_player_volume_mute,entity_id,true|false
+		if len(args) > 1 {
+			s.callService("media_player", "volume_mute", args[0], map[string]interface{}{"is_volume_muted": strings.ToLower(args[1]) == "true"})
+		}
+	case "media_player_select_sound_mode":
 		if len(args) > 1 {
-			// This is tricky without a proper JSON parser for the arg if it contains commas.
-			// But assuming basic usage or simple strings.
-			// Ideally we should just pass it through if possible or implement specific logic.
-			// For now, let's just log it as not fully supported or try simple case.
-			log.Printf("media_player_play_media not fully implemented for complex JSON args yet")
+			s.callService("media_player", "select_sound_mode", args[0], map[string]interface{}{"sound_mode": args[1]})
 		}
+	case "media_player_join":
+		// format: media_player_join,leader_entity_id,member1[,member2...]
+		// Members may also be separated with '|'.
+		if len(args) > 1 {
+			var members []string
+			for _, arg := range args[1:] {
+				for _, m := range strings.Split(arg, "|") {
+					if m = strings.TrimSpace(m); m != "" {
+						members = append(members, m)
+					}
+				}
+			}
+			s.callService("media_player", "join", args[0], map[string]interface{}{"group_members": members})
+		}
+	case "media_player_unjoin":
+		if len(args) > 0 {
+			s.callService("media_player", "unjoin", args[0], nil)
+		}
+	case "media_player_browse_media":
+		// format: media_player_browse_media,entity_id[,content_type,content_id][,page]
+		if len(args) > 0 {
+			contentType, contentID, page := "", "", 1
+			rest := args[1:]
+			if len(rest) >= 2 {
+				contentType, contentID = rest[0], rest[1]
+				rest = rest[2:]
+			}
+			if len(rest) > 0 {
+				page, _ = strconv.Atoi(rest[0])
+			}
+			s.browseMedia(conn, args[0], contentType, contentID, page)
+		}
+	case "list_areas":
+		// format: list_areas[,page]
+		s.listAreas(conn, args)
+	case "list_devices":
+		// format: list_devices[,page]
+		s.listDevices(conn, args)
+	case "list_entities":
+		// format: list_entities[,domain][,page]
+		s.listEntities(conn, args)
 	// Add other commands as needed based on hass_savant.rb
+	// and list them in Commands (commands.go)
 	default:
 		log.Printf("Unknown command: %s", cmd)
 	}
 }
 
+// callService sends a service call. entityID is the command's target
+// argument, see parseTarget for the accepted forms.
 func (s *Server) callService(domain, service, entityID string, data map[string]interface{}) {
 	payload := map[string]interface{}{
 		"type":    "call_service",
 		"domain":  domain,
 		"service": service,
-		"target": map[string]interface{}{
-			"entity_id": entityID,
-		},
+	}
+	// Services such as notify.* take no target
+	if target := parseTarget(entityID); target != nil {
+		payload["target"] = target
 	}
 	if data != nil {
 		payload["service_data"] = data
 	}
 	s.haClient.SendCommand(payload)
 }
+
+// onOff calls a generic turn_on, turn_off or toggle service, unless the
+// target holds scenes, scripts or automations the policy refuses.
+func (s *Server) onOff(cmd, domain, service, target string) {
+	if !s.serviceAllowed(domain, service, target, nil) {
+		log.Printf("%s,%s is disabled by configuration", cmd, target)
+		return
+	}
+	s.callService(domain, service, target, nil)
+}
+
+// serviceAllowed applies the scene, script and automation policy to a
+// generic service call. homeassistant.* services act on entities of any
+// domain, so for them the target's domains are checked instead.
+func (s *Server) serviceAllowed(domain, service, target string, data map[string]interface{}) bool {
+	policy := s.current().policy
+	if domain != "homeassistant" {
+		return policy.AllowsService(domain, service)
+	}
+	if id, ok := data["entity_id"].(string); ok && id != "" {
+		target += "|" + id
+	}
+	entities, ok := targetEntities(target)
+	if !ok {
+		return policy.AllowsAreaTargets()
+	}
+	for _, e := range entities {
+		if d, _, found := strings.Cut(e, "."); found && !policy.AllowsService(d, service) {
+			return false
+		}
+	}
+	return true
+}
+
+// moveShades moves the shades in target to a Savant level (0 = closed,
+// 100 = open). Shades with an override are positioned one by one through
+// it; the rest are sent together with service, which is given the
+// position only when it is set_cover_position.
+func (s *Server) moveShades(target string, level int, service string) {
+	entities, ok := targetEntities(target)
+	var rest []string
+	for _, e := range entities {
+		if o, found := s.current().shadeOverrides[e]; found {
+			s.callService("cover", "set_cover_position", e, map[string]interface{}{"position": o.ToHA(level)})
+		} else {
+			rest = append(rest, e)
+		}
+	}
+	if ok {
+		if len(rest) == 0 {
+			return
+		}
+		target = strings.Join(rest, "|")
+	}
+	var data map[string]interface{}
+	if service == "set_cover_position" {
+		data = map[string]interface{}{"position": level}
+	}
+	s.callService("cover", service, target, data)
+}
@@ -0,0 +1,84 @@
+package savant
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/metrics"
+)
+
+// newTestServer returns a server on an unconnected HA client, and a func
+// that reports how many service calls it has queued so far.
+func newTestServer(t *testing.T, policy config.Policy) (*Server, func() string) {
+	t.Helper()
+	client := ha.NewClient("", "", func(string) {})
+	registry := metrics.NewRegistry()
+	client.RegisterMetrics(registry)
+	s := NewServer(0, &config.Config{Policy: policy}, client)
+
+	calls := func() string {
+		var out strings.Builder
+		registry.WriteText(&out)
+		sc := bufio.NewScanner(strings.NewReader(out.String()))
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(sc.Text(), "bridge_ha_service_calls_total "); ok {
+				return v
+			}
+		}
+		t.Fatal("bridge_ha_service_calls_total not found")
+		return ""
+	}
+	return s, calls
+}
+
+// allPolicies enables every command covered by the policy.
+var allPolicies = config.Policy{GenericCallService: true, SceneActivate: true, ScriptRun: true, AutomationTrigger: true, AutomationToggle: true}
+
+func TestOnOffPolicy(t *testing.T) {
+	all := allPolicies
+	without := func(change func(p *config.Policy)) config.Policy {
+		p := all
+		change(&p)
+		return p
+	}
+	noScripts := without(func(p *config.Policy) { p.ScriptRun = false })
+	noScenes := without(func(p *config.Policy) { p.SceneActivate = false })
+	noToggle := without(func(p *config.Policy) { p.AutomationToggle = false })
+	noTrigger := without(func(p *config.Policy) { p.AutomationTrigger = false })
+
+	tests := []struct {
+		name    string
+		policy  config.Policy
+		command string
+		allowed bool
+	}{
+		{"script through switch_on", noScripts, "switch_on,script.x", false},
+		{"script among lights", noScripts, "switch_on,light.a|script.x", false},
+		{"script through switch_off", noScripts, "switch_off,script.x", false},
+		{"scene through switch_on", noScenes, "switch_on,scene.y", false},
+		{"scene through homeassistant_toggle", noScenes, "homeassistant_toggle,scene.y", false},
+		{"automation through toggle", noToggle, "toggle,automation.z", false},
+		{"automation through switch_on", noToggle, "switch_on,automation.z", false},
+		{"area through switch_on", noTrigger, "switch_on,area:kitchen", false},
+		{"device through toggle", noScenes, "toggle,device:abc", false},
+		{"light with scripts off", noScripts, "switch_on,light.a", true},
+		{"script with scripts on", all, "switch_on,script.x", true},
+		{"area with every policy on", all, "switch_off,area:kitchen", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, calls := newTestServer(t, tt.policy)
+			s.handleCommand(nil, tt.command)
+			want := "0"
+			if tt.allowed {
+				want = "1"
+			}
+			if got := calls(); got != want {
+				t.Errorf("%s queued %s service calls, want %s", tt.command, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package savant
+
+import (
+	"context"
+	"log"
+)
+
+// Shutdown stops accepting connections, sends bridge_shutdown to connected
+// clients, waits for commands being handled to finish (or ctx to be done)
+// and closes the connections.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.clientsMu.Lock()
+	s.closing = true
+	listener := s.listener
+	s.clientsMu.Unlock()
+
+	if listener != nil {
+		listener.Close()
+	}
+	s.Broadcast("bridge_shutdown\n")
+
+	finished := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.clientsMu.Lock()
+	for conn, ip := range s.clients {
+		log.Printf("Savant: Closing connection from %s", ip)
+		conn.Close()
+	}
+	s.clientsMu.Unlock()
+	return err
+}
+
+func (s *Server) isClosing() bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	return s.closing
+}
+
+// beginCommand registers a command with inflight, unless the server is
+// shutting down. Registering under the lock keeps Add from racing Wait.
+func (s *Server) beginCommand() bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
@@ -0,0 +1,91 @@
+package savant
+
+import (
+	"log"
+	"strings"
+)
+
+// targetKeys maps the prefixes Savant may put in front of a target to the
+// HA target field they fill.
+var targetKeys = map[string]string{
+	"area":   "area_id",
+	"device": "device_id",
+	"label":  "label_id",
+	"floor":  "floor_id",
+}
+
+// parseTarget turns the entity argument of a command into an HA service
+// target. The argument may name several targets separated by '|', each
+// either an entity ID or one of area:, device:, label: or floor: followed
+// by the registry ID:
+//
+//	light.kitchen|light.pantry
+//	area:kitchen|light.hallway
+//
+// Fields with a single value are sent as a string, others as a list.
+// It returns nil when the argument names nothing.
+func parseTarget(spec string) map[string]interface{} {
+	ids := make(map[string][]string)
+	var order []string
+	for _, part := range strings.Split(spec, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := "entity_id"
+		if prefix, id, ok := strings.Cut(part, ":"); ok {
+			if k, known := targetKeys[prefix]; known {
+				key, part = k, strings.TrimSpace(id)
+			}
+		}
+		if _, seen := ids[key]; !seen {
+			order = append(order, key)
+		}
+		ids[key] = append(ids[key], part)
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	target := make(map[string]interface{}, len(order))
+	for _, key := range order {
+		if len(ids[key]) == 1 {
+			target[key] = ids[key][0]
+		} else {
+			target[key] = ids[key]
+		}
+	}
+	return target
+}
+
+// targetEntities returns the plain entity IDs named by a target argument,
+// and false if it also contains area, device, label or floor targets.
+func targetEntities(spec string) ([]string, bool) {
+	var entities []string
+	for _, part := range strings.Split(spec, "|") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if prefix, _, ok := strings.Cut(part, ":"); ok {
+			if _, known := targetKeys[prefix]; known {
+				return nil, false
+			}
+		}
+		entities = append(entities, part)
+	}
+	return entities, true
+}
+
+// entityList returns the entities of a target for commands that check or
+// address each entity on its own (codes, helper validation, cameras).
+// Area, device, label and floor targets cannot be expanded here, so they
+// are refused and logged.
+func entityList(cmd, spec string) ([]string, bool) {
+	entities, ok := targetEntities(spec)
+	if !ok || len(entities) == 0 {
+		log.Printf("%s: %q must name entities, not an area, device, label or floor", cmd, spec)
+		return nil, false
+	}
+	return entities, true
+}
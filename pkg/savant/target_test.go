@@ -0,0 +1,47 @@
+package savant
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec string
+		want map[string]interface{}
+	}{
+		{"", nil},
+		{" | ", nil},
+		{"light.kitchen", map[string]interface{}{"entity_id": "light.kitchen"}},
+		{"light.kitchen| light.pantry ", map[string]interface{}{"entity_id": []string{"light.kitchen", "light.pantry"}}},
+		{"area:kitchen|light.hallway", map[string]interface{}{"area_id": "kitchen", "entity_id": "light.hallway"}},
+		{"area:kitchen|area:hall", map[string]interface{}{"area_id": []string{"kitchen", "hall"}}},
+		{"device:abc|label:night|floor: upstairs", map[string]interface{}{"device_id": "abc", "label_id": "night", "floor_id": "upstairs"}},
+		// Unknown prefixes are left to HA as entity IDs.
+		{"zone:home", map[string]interface{}{"entity_id": "zone:home"}},
+	}
+	for _, tt := range tests {
+		if got := parseTarget(tt.spec); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseTarget(%q) = %#v, want %#v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestTargetEntities(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+		ok   bool
+	}{
+		{"", nil, true},
+		{"lock.front", []string{"lock.front"}, true},
+		{"lock.front|lock.back", []string{"lock.front", "lock.back"}, true},
+		{"lock.front|area:hall", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := targetEntities(tt.spec)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.ok {
+			t.Errorf("targetEntities(%q) = %q, %v, want %q, %v", tt.spec, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// handleArtwork serves /artwork/<entity_id>. The entity's current
+// entity_picture is looked up in the state cache, so only pictures HA
+// publishes can be fetched through the bridge.
+func (s *Server) handleArtwork(w http.ResponseWriter, r *http.Request) {
+	entityID := strings.TrimPrefix(r.URL.Path, "/artwork/")
+	st, ok := s.haClient.Entity(entityID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	picture, _ := st.Attributes["entity_picture"].(string)
+	if !strings.HasPrefix(picture, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	img, ok := s.artworkCache.get(picture)
+	if !ok {
+		body, contentType, err := s.fetch(picture)
+		if err != nil {
+			log.Printf("HTTP: Artwork for %s failed: %v", entityID, err)
+			http.Error(w, "artwork unavailable", http.StatusBadGateway)
+			return
+		}
+		img = cachedImage{data: body, contentType: contentType}
+		if s.artworkSize > 0 || (s.artworkFormat != "" && s.artworkFormat != "original") {
+			if out, ct, err := transcode(body, s.artworkSize, s.artworkFormat); err == nil {
+				img = cachedImage{data: out, contentType: ct}
+			} else {
+				log.Printf("HTTP: Artwork for %s served unchanged: %v", entityID, err)
+			}
+		}
+		s.artworkCache.put(picture, img)
+	}
+
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Cache-Control", "max-age=60")
+	w.Write(img.data)
+}
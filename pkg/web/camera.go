@@ -0,0 +1,33 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"strings"
+)
+
+// handleCamera serves /camera/<entity_id> with a fresh snapshot from HA's
+// camera_proxy. Only cameras listed in the configuration are served.
+func (s *Server) handleCamera(w http.ResponseWriter, r *http.Request) {
+	entityID := strings.TrimPrefix(r.URL.Path, "/camera/")
+	if !s.cameraAllowed(entityID) {
+		http.NotFound(w, r)
+		return
+	}
+
+	img, ok := s.snapshotCache.get(entityID)
+	if !ok {
+		body, contentType, err := s.fetch("/api/camera_proxy/" + entityID)
+		if err != nil {
+			log.Printf("HTTP: Snapshot for %s failed: %v", entityID, err)
+			http.Error(w, "snapshot unavailable", http.StatusBadGateway)
+			return
+		}
+		img = cachedImage{data: body, contentType: contentType}
+		s.snapshotCache.put(entityID, img)
+	}
+
+	w.Header().Set("Content-Type", img.contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(img.data)
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+	"sync"
+	"time"
+)
+
+const maxImageBytes = 10 << 20
+
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("image larger than %d bytes", limit)
+	}
+	return data, nil
+}
+
+// transcode scales an image so that its longest edge is at most maxEdge
+// (0 keeps the size) and re-encodes it as jpeg or png. Any other format
+// keeps the source encoding where possible and falls back to jpeg.
+func transcode(data []byte, maxEdge int, format string) ([]byte, string, error) {
+	src, srcFormat, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if maxEdge > 0 {
+		src = scaleDown(src, maxEdge)
+	}
+	if format == "" || format == "original" {
+		format = srcFormat
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&buf, src)
+		return buf.Bytes(), "image/png", err
+	default:
+		err = jpeg.Encode(&buf, src, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), "image/jpeg", err
+	}
+}
+
+// scaleDown shrinks src with a box filter; images already small enough are
+// returned unchanged.
+func scaleDown(src image.Image, maxEdge int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return src
+	}
+	dw, dh := maxEdge, h*maxEdge/w
+	if h > w {
+		dw, dh = w*maxEdge/h, maxEdge
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+cr, g+cg, bl+cb, a+ca
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = uint8(a / n >> 8)
+		}
+	}
+	return dst
+}
+
+type cachedImage struct {
+	data        []byte
+	contentType string
+	expires     time.Time
+}
+
+// imageCache keeps recently served images so that several touch panels
+// showing the same artwork only cost one request to HA.
+type imageCache struct {
+	mu      sync.Mutex
+	max     int
+	ttl     time.Duration
+	entries map[string]cachedImage
+}
+
+func newImageCache(max int, ttl time.Duration) *imageCache {
+	return &imageCache{max: max, ttl: ttl, entries: make(map[string]cachedImage)}
+}
+
+func (c *imageCache) get(key string) (cachedImage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	img, ok := c.entries[key]
+	if !ok || time.Now().After(img.expires) {
+		delete(c.entries, key)
+		return cachedImage{}, false
+	}
+	return img, true
+}
+
+func (c *imageCache) put(key string, img cachedImage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= c.max {
+		// Drop expired entries first, then the one closest to expiry
+		oldest := ""
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			} else if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+				oldest = k
+			}
+		}
+		if len(c.entries) >= c.max && oldest != "" {
+			delete(c.entries, oldest)
+		}
+	}
+	img.expires = now.Add(c.ttl)
+	c.entries[key] = img
+}
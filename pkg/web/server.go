@@ -0,0 +1,176 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/config"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/ha"
+	"github.com/rickyangkai/HomeassistantTCPBridge/pkg/metrics"
+)
+
+// Server is the bridge's small HTTP endpoint. It serves Home Assistant
+// resources that need a token (artwork, camera snapshots) as plain URLs
+// Savant can fetch.
+type Server struct {
+	port       int
+	mu         sync.RWMutex // protects whitelist and cameras
+	whitelist  []string
+	haClient   *ha.Client
+	haURL      string
+	token      string
+	httpClient *http.Client
+	mux        *http.ServeMux
+	httpServer *http.Server
+
+	artworkSize   int
+	artworkFormat string
+	artworkCache  *imageCache
+
+	cameras       map[string]bool
+	snapshotCache *imageCache
+}
+
+func NewServer(port int, cfg *config.Config, haClient *ha.Client) *Server {
+	s := &Server{
+		port:          port,
+		whitelist:     cfg.Whitelist,
+		haClient:      haClient,
+		haURL:         cfg.HAHTTPURL,
+		token:         cfg.SupervisorToken,
+		httpClient:    newHTTPClient(cfg),
+		mux:           http.NewServeMux(),
+		artworkSize:   cfg.Options.ArtworkSize,
+		artworkFormat: cfg.Options.ArtworkFormat,
+		artworkCache:  newImageCache(64, 10*time.Minute),
+		cameras:       cfg.Cameras,
+		// Panels poll snapshots; a short cache keeps HA from being hit
+		// once per panel.
+		snapshotCache: newImageCache(32, 2*time.Second),
+	}
+	s.mux.HandleFunc("/artwork/", s.handleArtwork)
+	s.mux.HandleFunc("/camera/", s.handleCamera)
+	s.httpServer = &http.Server{
+		Addr:    fmt.Sprintf("0.0.0.0:%d", port),
+		Handler: s.withWhitelist(s.mux),
+	}
+	return s
+}
+
+// newHTTPClient returns the client used for HA's REST API, trusting the
+// configured CA bundle in standalone mode.
+func newHTTPClient(cfg *config.Config) *http.Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if cfg.TLSConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = cfg.TLSConfig
+		client.Transport = transport
+	}
+	return client
+}
+
+func (s *Server) Start() {
+	log.Printf("HTTP: Listening on %s", s.httpServer.Addr)
+	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("HTTP: Server stopped: %v", err)
+	}
+}
+
+// Shutdown stops the HTTP server, letting requests in progress finish
+// until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// HandleMetrics serves r at /metrics, behind the same whitelist as the
+// other endpoints.
+func (s *Server) HandleMetrics(r *metrics.Registry) {
+	s.mux.Handle("/metrics", r.Handler())
+}
+
+// withWhitelist applies the Savant client IP whitelist to HTTP requests.
+func (s *Server) withWhitelist(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowed(r.RemoteAddr) {
+			log.Printf("HTTP: Access denied for %s", r.RemoteAddr)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Reload applies the reloadable options: the whitelist and the camera list.
+func (s *Server) Reload(cfg *config.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.whitelist = cfg.Whitelist
+	s.cameras = cfg.Cameras
+}
+
+func (s *Server) cameraAllowed(entityID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cameras[entityID]
+}
+
+func (s *Server) allowed(remoteAddr string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.whitelist) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	for _, ip := range s.whitelist {
+		if ip == host {
+			return true
+		}
+	}
+	return false
+}
+
+// fetch GETs a path from Home Assistant with the bridge's token.
+func (s *Server) fetch(path string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.haURL+path, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("HA returned %s", resp.Status)
+	}
+	body, err := readLimited(resp.Body, maxImageBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// PublicURL returns the base URL Savant should use for this endpoint: the
+// configured public_url, or the host's outbound address and HTTP port.
+func (s *Server) PublicURL(configured string) string {
+	if configured != "" {
+		return strings.TrimRight(configured, "/")
+	}
+	host := "127.0.0.1"
+	// No packets are sent; this only selects the outbound interface.
+	if conn, err := net.Dial("udp", "8.8.8.8:80"); err == nil {
+		host = conn.LocalAddr().(*net.UDPAddr).IP.String()
+		conn.Close()
+	}
+	return fmt.Sprintf("http://%s:%d", host, s.port)
+}